@@ -0,0 +1,93 @@
+package gotemp
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	ampScript    = regexp.MustCompile(`(?is)<script\b([^>]*)>.*?</script\s*>`)
+	ampStyle     = regexp.MustCompile(`(?is)<style\b([^>]*)>(.*?)</style\s*>`)
+	ampTag       = regexp.MustCompile(`<[a-zA-Z][^>]*>`)
+	ampStyleAttr = regexp.MustCompile(`(?i)\sstyle\s*=\s*"([^"]*)"`)
+	ampClassAttr = regexp.MustCompile(`(?i)(\sclass\s*=\s*")([^"]*)"`)
+)
+
+func AMP(w io.Writer) io.Writer {
+	return &ampWriter{w: w}
+}
+
+type ampWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (aw *ampWriter) Write(p []byte) (int, error) {
+	return aw.buf.Write(p)
+}
+
+func (aw *ampWriter) Close() error {
+	_, err := aw.w.Write(ampTransform(aw.buf.Bytes()))
+	return err
+}
+
+func ampTransform(src []byte) []byte {
+	out := ampScript.ReplaceAllFunc(src, func(m []byte) []byte {
+		attrs := ampScript.FindSubmatch(m)[1]
+		if bytes.Contains(attrs, []byte("cdn.ampproject.org")) || bytes.Contains(bytes.ToLower(attrs), []byte("application/ld+json")) {
+			return m
+		}
+		return nil
+	})
+
+	var css strings.Builder
+	out = ampStyle.ReplaceAllFunc(out, func(m []byte) []byte {
+		match := ampStyle.FindSubmatch(m)
+		if bytes.Contains(match[1], []byte("amp-boilerplate")) {
+			return m
+		}
+		css.Write(bytes.TrimSpace(match[2]))
+		return nil
+	})
+
+	classes := map[string]string{}
+	out = ampTag.ReplaceAllFunc(out, func(tag []byte) []byte {
+		match := ampStyleAttr.FindSubmatchIndex(tag)
+		if match == nil {
+			return tag
+		}
+		style := strings.TrimSpace(html.UnescapeString(string(tag[match[2]:match[3]])))
+		tag = append(tag[:match[0]:match[0]], tag[match[1]:]...)
+		if style == "" {
+			return tag
+		}
+		class, ok := classes[style]
+		if !ok {
+			class = fmt.Sprintf("gotemp-amp-%d", len(classes)+1)
+			classes[style] = class
+			fmt.Fprintf(&css, ".%s{%s}", class, style)
+		}
+		if ampClassAttr.Match(tag) {
+			return ampClassAttr.ReplaceAll(tag, []byte(`$1$2 `+class+`"`))
+		}
+		end := len(tag) - 1
+		if tag[end-1] == '/' {
+			end--
+		}
+		return []byte(string(tag[:end]) + ` class="` + class + `"` + string(tag[end:]))
+	})
+
+	if css.Len() == 0 {
+		return out
+	}
+	custom := "<style amp-custom>" + css.String() + "</style>"
+	i := indexFold(out, "</head>")
+	if i < 0 {
+		return append([]byte(custom), out...)
+	}
+	return []byte(string(out[:i]) + custom + string(out[i:]))
+}
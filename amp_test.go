@@ -0,0 +1,60 @@
+package gotemp_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestAMPTransform(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"root.html":             `{{ define "__start" }}<html><head><script async src="https://cdn.ampproject.org/v0.js"></script><style>h1{margin:0}</style></head><body>{{ end }}{{ define "__end" }}</body></html>{{ end }}`,
+		"partials/note.html":    `<p style="color: red">{{ . }}</p>`,
+		"pages/home/index.html": `{{ define "content" }}<h1 class="title" style="color: red">{{ . }}</h1>{{ partial "note.html" "hi" }}<script>alert(1)</script>{{ end }}`,
+	})
+	want := `<html><head><script async src="https://cdn.ampproject.org/v0.js"></script>` +
+		`<style amp-custom>h1{margin:0}.gotemp-amp-1{color: red}</style></head><body>` +
+		`<h1 class="title gotemp-amp-1">Ada</h1><p class="gotemp-amp-1">hi</p></body></html>`
+
+	g, err := gotemp.New(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	amp := gotemp.AMP(&buf)
+	if err := g.RenderPage(amp, "app_layout", "home/index.html", "Ada"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := amp.(io.Closer).Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != want {
+		t.Errorf("expected the disallowed script stripped and styles moved, got %q, want %q", buf.String(), want)
+	}
+
+	for name, opt := range map[string]gotemp.Option{
+		"middleware": gotemp.WithOutputMiddleware(gotemp.AMP),
+		"option":     gotemp.WithAMPTransform(true),
+	} {
+		g, err = gotemp.New(dir, opt)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		buf.Reset()
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", "Ada"); err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		if buf.String() != want {
+			t.Errorf("%s: expected pages to be transformed, got %q, want %q", name, buf.String(), want)
+		}
+		html, err := g.RenderPartialHTML("note.html", "hi")
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		if html != `<p style="color: red">hi</p>` {
+			t.Errorf("%s: expected partials to stay untransformed, got %q", name, html)
+		}
+	}
+}
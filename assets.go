@@ -0,0 +1,138 @@
+package gotemp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var assetFuncs = []string{"requireCSS", "requireJS", "emitCSS", "emitJS"}
+
+var assetMarker = regexp.MustCompile(`<!--gotemp:(require|emit)-(css|js):([0-9a-f]*)-->`)
+
+func assetHelpers() template.FuncMap {
+	return template.FuncMap{
+		"requireCSS": func(href string) template.HTML { return assetMarkerFor("require", "css", href) },
+		"requireJS":  func(src string) template.HTML { return assetMarkerFor("require", "js", src) },
+		"emitCSS":    func() template.HTML { return assetMarkerFor("emit", "css", "") },
+		"emitJS":     func() template.HTML { return assetMarkerFor("emit", "js", "") },
+	}
+}
+
+func assetMarkerFor(action, kind, url string) template.HTML {
+	return template.HTML("<!--gotemp:" + action + "-" + kind + ":" + hex.EncodeToString([]byte(url)) + "-->")
+}
+
+type assetWriter struct {
+	w        io.Writer
+	buf      bytes.Buffer
+	recorder assetRecorder
+	stream   bool
+	required map[string][]string
+	emitted  map[string]bool
+}
+
+func (aw *assetWriter) Write(p []byte) (int, error) {
+	if !aw.stream {
+		return aw.buf.Write(p)
+	}
+	aw.require(p)
+	if _, err := aw.w.Write(aw.resolve(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (aw *assetWriter) Close() error {
+	if aw.stream {
+		return nil
+	}
+	aw.require(aw.buf.Bytes())
+	if aw.recorder != nil {
+		aw.recorder.recordAssets(aw.required)
+	}
+	_, err := aw.w.Write(aw.resolve(aw.buf.Bytes()))
+	return err
+}
+
+func (aw *assetWriter) require(p []byte) {
+	if aw.required == nil {
+		aw.required = map[string][]string{}
+	}
+	for _, match := range assetMarker.FindAllSubmatch(p, -1) {
+		if string(match[1]) != "require" {
+			continue
+		}
+		url, err := hex.DecodeString(string(match[3]))
+		if err != nil {
+			continue
+		}
+		kind := string(match[2])
+		if !slices.Contains(aw.required[kind], string(url)) {
+			aw.required[kind] = append(aw.required[kind], string(url))
+		}
+	}
+}
+
+func (aw *assetWriter) resolve(p []byte) []byte {
+	if aw.emitted == nil {
+		aw.emitted = map[string]bool{}
+	}
+	return assetMarker.ReplaceAllFunc(p, func(marker []byte) []byte {
+		match := assetMarker.FindSubmatch(marker)
+		kind := string(match[2])
+		if string(match[1]) != "emit" || aw.emitted[kind] {
+			return nil
+		}
+		aw.emitted[kind] = true
+		var tags bytes.Buffer
+		for _, url := range aw.required[kind] {
+			if kind == "css" {
+				tags.WriteString(`<link rel="stylesheet" href="` + template.HTMLEscapeString(url) + `">`)
+			} else {
+				tags.WriteString(`<script src="` + template.HTMLEscapeString(url) + `"></script>`)
+			}
+		}
+		return tags.Bytes()
+	})
+}
+
+type assetRecorder interface {
+	recordAssets(required map[string][]string)
+}
+
+type assetBuffer struct {
+	bytes.Buffer
+	required map[string][]string
+}
+
+func (b *assetBuffer) recordAssets(required map[string][]string) {
+	if b.required == nil {
+		b.required = make(map[string][]string)
+	}
+	for kind, urls := range required {
+		for _, url := range urls {
+			if !slices.Contains(b.required[kind], url) {
+				b.required[kind] = append(b.required[kind], url)
+			}
+		}
+	}
+}
+
+var preloadAs = map[string]string{"css": "style", "js": "script"}
+
+var linkURLEscaper = strings.NewReplacer("<", "%3C", ">", "%3E")
+
+func (b *assetBuffer) preloadLinks() []string {
+	var links []string
+	for _, kind := range []string{"css", "js"} {
+		for _, url := range b.required[kind] {
+			links = append(links, "<"+linkURLEscaper.Replace(url)+">; rel=preload; as="+preloadAs[kind])
+		}
+	}
+	return links
+}
@@ -0,0 +1,110 @@
+package gotemp_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestAssetDependencies(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html": `{{ define "app_layout" }}<head>{{ emitCSS }}</head>` +
+			`<body>{{ block "content" . }}{{ end }}{{ emitJS }}</body>{{ end }}`,
+		"partials/widget.html": `{{ requireCSS "/static/widget.css" }}{{ requireJS "/static/widget.js" }}<div class="widget">{{ . }}</div>`,
+		"pages/home/index.html": `{{ define "content" }}{{ requireCSS "/static/home.css" }}` +
+			`{{ partial "widget.html" "one" }}{{ partial "widget.html" "two" }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := `<head><link rel="stylesheet" href="/static/home.css"><link rel="stylesheet" href="/static/widget.css"></head>` +
+		`<body><div class="widget">one</div><div class="widget">two</div><script src="/static/widget.js"></script></body>`
+	if buf.String() != want {
+		t.Errorf("expected deduplicated assets in the head\nwant %q\ngot  %q", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := g.RenderPartial(&buf, "widget.html", "solo"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != `<div class="widget">solo</div>` {
+		t.Errorf("expected asset markers to be stripped without emit, got %q", buf.String())
+	}
+}
+
+func TestAssetDependenciesEscapeURLs(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html":      `{{ define "app_layout" }}{{ emitCSS }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ requireCSS . }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", `a.css"><script>-->`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(buf.String(), "<script>") || !strings.Contains(buf.String(), `href="a.css&#34;&gt;&lt;script&gt;--&gt;"`) {
+		t.Errorf("expected escaped asset URL, got %q", buf.String())
+	}
+}
+
+func TestPreloadLinks(t *testing.T) {
+	files := map[string]string{
+		"layouts/app.html": `{{ define "app_layout" }}<head>{{ emitCSS }}</head>` +
+			`<body>{{ block "content" . }}{{ end }}{{ emitJS }}</body>{{ end }}`,
+		"partials/widget.html": `{{ requireCSS "/static/widget.css" }}{{ requireJS "/static/widget.js" }}<div class="widget">{{ . }}</div>`,
+		"pages/home/index.html": `{{ define "content" }}{{ requireCSS "/static/home.css" }}` +
+			`{{ partial "widget.html" "one" }}{{ partial "widget.html" "two" }}{{ end }}`,
+		"pages/home/plain.html": `{{ define "content" }}Plain{{ end }}`,
+	}
+	want := []string{
+		"</static/home.css>; rel=preload; as=style",
+		"</static/widget.css>; rel=preload; as=style",
+		"</static/widget.js>; rel=preload; as=script",
+	}
+
+	for name, opts := range map[string][]gotemp.Option{
+		"uncached": {gotemp.WithPreloadLinks(true)},
+		"cached":   {gotemp.WithPreloadLinks(true), gotemp.WithRenderCache(8)},
+	} {
+		g, err := gotemp.New(writeTemplates(t, files), opts...)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		handler := g.Handler("app_layout")
+		for i := 0; i < 2; i++ {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/", nil))
+			if links := rec.Header().Values("Link"); !slices.Equal(links, want) {
+				t.Errorf("%s request %d: expected Link headers %q, got %q", name, i, want, links)
+			}
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/plain", nil))
+		if links := rec.Header().Values("Link"); len(links) != 0 {
+			t.Errorf("%s: expected no Link headers for a page without assets, got %q", name, links)
+		}
+	}
+
+	g, err := gotemp.New(writeTemplates(t, files))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	rec := httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/", nil))
+	if links := rec.Header().Values("Link"); len(links) != 0 {
+		t.Errorf("expected no Link headers without WithPreloadLinks, got %q", links)
+	}
+}
@@ -0,0 +1,37 @@
+package gotemp
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"strconv"
+	"text/template/parse"
+)
+
+const auditFunc = "_gotemp_audit"
+
+func (tc *Gotemp) logger() *slog.Logger {
+	if tc.log != nil {
+		return tc.log
+	}
+	return slog.Default()
+}
+
+func (tc *Gotemp) audit(location string, value any) any {
+	switch value.(type) {
+	case template.HTML, template.HTMLAttr, template.JS, template.JSStr, template.CSS, template.URL, template.Srcset:
+		tc.logger().Warn("gotemp: trusted content bypasses escaping",
+			"location", location,
+			"type", fmt.Sprintf("%T", value),
+		)
+	}
+	return value
+}
+
+func auditLocation(tree *parse.Tree, node *parse.ActionNode) ([]parse.Node, bool) {
+	if pipelineStartsWith(node, "partial", "requireCSS", "requireJS", "emitCSS", "emitJS") {
+		return nil, false
+	}
+	location, _ := tree.ErrorContext(node)
+	return []parse.Node{&parse.StringNode{NodeType: parse.NodeString, Pos: node.Pos, Quoted: strconv.Quote(location), Text: location}}, true
+}
@@ -0,0 +1,61 @@
+package gotemp_test
+
+import (
+	"bytes"
+	"html/template"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestEscapeDebug(t *testing.T) {
+	files := map[string]string{
+		"partials/badge.html": `<span>{{ . }}</span>`,
+		"pages/home/index.html": `{{ define "content" }}{{ .Plain }}
+{{ .Trusted }}
+{{ partial "badge.html" "new" }}
+{{ raw "partials/badge.html" }}{{ end }}`,
+	}
+	data := map[string]any{"Plain": "<b>plain</b>", "Trusted": template.HTML("<b>trusted</b>")}
+
+	var logs bytes.Buffer
+	g, err := gotemp.New(writeTemplates(t, files),
+		gotemp.WithEscapeDebug(true),
+		gotemp.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "&lt;b&gt;plain&lt;/b&gt;\n<b>trusted</b>") {
+		t.Errorf("expected output to be unchanged by auditing, got %q", buf.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two audit records, got %q", logs.String())
+	}
+	if !strings.Contains(lines[0], "location=index.html:2:") || !strings.Contains(lines[0], "type=template.HTML") {
+		t.Errorf("expected the trusted value to be reported, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "location=index.html:4:") {
+		t.Errorf("expected raw include to be reported, got %q", lines[1])
+	}
+
+	logs.Reset()
+	g, err = gotemp.New(writeTemplates(t, files), gotemp.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no audit records without WithEscapeDebug, got %q", logs.String())
+	}
+}
@@ -0,0 +1,32 @@
+package gotemp
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+)
+
+func (tc *Gotemp) RenderBlocks(w io.Writer, page string, blocks []string, data any) error {
+	return tc.renderBlocks(w, page, blocks, data, false)
+}
+
+func (tc *Gotemp) RenderBlocksOOB(w io.Writer, page string, blocks []string, data any) error {
+	return tc.renderBlocks(w, page, blocks, data, true)
+}
+
+func (tc *Gotemp) renderBlocks(w io.Writer, page string, blocks []string, data any, oob bool) error {
+	var buf bytes.Buffer
+	for _, block := range blocks {
+		if oob {
+			buf.WriteString(`<div id="` + template.HTMLEscapeString(block) + `" hx-swap-oob="true">`)
+		}
+		if err := tc.RenderBlock(&buf, "", page, block, data); err != nil {
+			return err
+		}
+		if oob {
+			buf.WriteString(`</div>`)
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
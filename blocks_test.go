@@ -0,0 +1,47 @@
+package gotemp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestRenderBlocks(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/cart/index.html": `{{ define "content" }}Cart{{ end }}` +
+			`{{ define "cart-count" }}{{ len .Items }}{{ end }}` +
+			`{{ define "cart-total" }}{{ .Total }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data := map[string]any{"Items": []string{"a", "b"}, "Total": "<9.99>"}
+
+	var buf strings.Builder
+	if err := g.RenderBlocks(&buf, "cart/index.html", []string{"cart-total", "cart-count"}, data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "&lt;9.99&gt;2" {
+		t.Errorf("expected the blocks in order, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.RenderBlocksOOB(&buf, "cart/index.html", []string{"cart-count", "cart-total"}, data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := `<div id="cart-count" hx-swap-oob="true">2</div><div id="cart-total" hx-swap-oob="true">&lt;9.99&gt;</div>`
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	err = g.RenderBlocks(&buf, "cart/index.html", []string{"cart-count", "missing"}, data)
+	if !errors.Is(err, gotemp.ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be written when a block fails, got %q", buf.String())
+	}
+}
@@ -0,0 +1,318 @@
+package gotemp
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	partialCacheSize = 1024
+	remoteCacheSize  = 1024
+)
+
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+type renderKey string
+
+type renderCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[renderKey]*list.Element
+	flights map[renderKey]*renderFlight
+	version int
+	scope   string
+	backend Cache
+}
+
+type renderEntry struct {
+	key     renderKey
+	output  *assetBuffer
+	expires time.Time
+}
+
+type renderFlight struct {
+	done   chan struct{}
+	output *assetBuffer
+	err    error
+}
+
+func newRenderCache(size int) *renderCache {
+	return &renderCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[renderKey]*list.Element),
+		flights: make(map[renderKey]*renderFlight),
+	}
+}
+
+func newBackendRenderCache(backend Cache) *renderCache {
+	c := newRenderCache(0)
+	c.backend = backend
+	return c
+}
+
+type memoryCache struct {
+	cache *renderCache
+}
+
+func NewMemoryCache(size int) Cache {
+	return &memoryCache{cache: newRenderCache(size)}
+}
+
+func (m *memoryCache) Get(key string) ([]byte, bool) {
+	m.cache.mu.Lock()
+	defer m.cache.mu.Unlock()
+	output, ok := m.cache.get(renderKey(key))
+	if !ok {
+		return nil, false
+	}
+	return slices.Clone(output.Bytes()), true
+}
+
+func (m *memoryCache) Set(key string, value []byte, ttl time.Duration) {
+	var output assetBuffer
+	output.Write(value)
+	m.cache.mu.Lock()
+	defer m.cache.mu.Unlock()
+	m.cache.add(renderKey(key), &output, ttl)
+}
+
+type cachedRender struct {
+	Output string              `json:"output"`
+	Assets map[string][]string `json:"assets,omitempty"`
+}
+
+func (c *renderCache) backendGet(key renderKey) (*assetBuffer, bool) {
+	value, ok := c.backend.Get(string(key))
+	if !ok {
+		return nil, false
+	}
+	var cached cachedRender
+	if err := json.Unmarshal(value, &cached); err != nil {
+		return nil, false
+	}
+	output := &assetBuffer{required: cached.Assets}
+	output.WriteString(cached.Output)
+	return output, true
+}
+
+func (c *renderCache) backendSet(key renderKey, output *assetBuffer, ttl time.Duration) {
+	value, err := json.Marshal(cachedRender{Output: output.String(), Assets: output.required})
+	if err == nil {
+		c.backend.Set(string(key), value, ttl)
+	}
+}
+
+func (tc *Gotemp) WarmCache(layout string, dataFor func(page string) any) (int, error) {
+	if tc.renderCache == nil {
+		return 0, errors.New("warm cache: the render cache is not enabled, see WithRenderCache")
+	}
+	warmed := 0
+	var errs []error
+	for _, page := range tc.ListPages() {
+		var data any
+		if dataFor != nil {
+			data = dataFor(page)
+		}
+		if _, ok := newRenderKey("", layout, page, data); !ok {
+			errs = append(errs, fmt.Errorf("warm %s: data of type %T cannot be cached", page, data))
+			continue
+		}
+		if err := tc.RenderPage(io.Discard, layout, page, data); err != nil {
+			errs = append(errs, fmt.Errorf("warm %s: %w", page, err))
+			continue
+		}
+		warmed++
+	}
+	return warmed, errors.Join(errs...)
+}
+
+func (c *renderCache) render(w io.Writer, layout, page string, data any, ttl time.Duration, execute func(io.Writer) error) error {
+	c.mu.Lock()
+	scope := c.scope
+	c.mu.Unlock()
+	key, ok := newRenderKey(scope, layout, page, data)
+	if !ok {
+		return execute(w)
+	}
+	output, err := c.load(key, ttl, func() (*assetBuffer, error) {
+		var buf assetBuffer
+		if err := execute(&buf); err != nil {
+			return nil, err
+		}
+		return &buf, nil
+	})
+	if err != nil {
+		return err
+	}
+	if recorder, ok := w.(assetRecorder); ok && output.required != nil {
+		recorder.recordAssets(output.required)
+	}
+	_, err = w.Write(output.Bytes())
+	return err
+}
+
+func (c *renderCache) load(key renderKey, ttl time.Duration, render func() (*assetBuffer, error)) (*assetBuffer, error) {
+	if c.backend != nil {
+		if output, ok := c.backendGet(key); ok {
+			return output, nil
+		}
+	}
+	c.mu.Lock()
+	if output, ok := c.get(key); ok {
+		c.mu.Unlock()
+		return output, nil
+	}
+	if flight, ok := c.flights[key]; ok {
+		c.mu.Unlock()
+		<-flight.done
+		return flight.output, flight.err
+	}
+	flight := &renderFlight{done: make(chan struct{})}
+	c.flights[key] = flight
+	version := c.version
+	c.mu.Unlock()
+
+	flight.output, flight.err = render()
+
+	c.mu.Lock()
+	if c.flights[key] == flight {
+		delete(c.flights, key)
+	}
+	store := flight.err == nil && c.version == version
+	if store && c.backend == nil {
+		c.add(key, flight.output, ttl)
+	}
+	c.mu.Unlock()
+	if store && c.backend != nil {
+		c.backendSet(key, flight.output, ttl)
+	}
+	close(flight.done)
+	return flight.output, flight.err
+}
+
+func newRenderKey(scope, layout, page string, data any) (renderKey, bool) {
+	if !jsonComplete(reflect.ValueOf(data), 0) {
+		return "", false
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", false
+	}
+	hash := sha256.New()
+	hash.Write([]byte(scope))
+	hash.Write([]byte{0})
+	hash.Write([]byte(layout))
+	hash.Write([]byte{0})
+	hash.Write([]byte(page))
+	hash.Write([]byte{0})
+	fmt.Fprintf(hash, "%T", data)
+	hash.Write([]byte{0})
+	hash.Write(encoded)
+	return renderKey(fmt.Sprintf("%s:%s:%x", layout, page, hash.Sum(nil))), true
+}
+
+var jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+
+func jsonComplete(v reflect.Value, depth int) bool {
+	if !v.IsValid() {
+		return true
+	}
+	if depth > 64 {
+		return false
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil() || jsonComplete(v.Elem(), depth+1)
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if (!field.IsExported() && !field.Anonymous) || field.Tag.Get("json") == "-" {
+				if !v.Field(i).IsZero() {
+					return false
+				}
+				continue
+			}
+			if !jsonComplete(v.Field(i), depth+1) {
+				return false
+			}
+		}
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			if !jsonComplete(iter.Value(), depth+1) {
+				return false
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if !jsonComplete(v.Index(i), depth+1) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (c *renderCache) get(key renderKey) (*assetBuffer, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*renderEntry)
+	if !entry.expires.IsZero() && !time.Now().Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.output, true
+}
+
+func (c *renderCache) add(key renderKey, output *assetBuffer, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*renderEntry)
+		entry.output, entry.expires = output, expires
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&renderEntry{key: key, output: output, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*renderEntry).key)
+	}
+}
+
+func (c *renderCache) setScope(scope string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scope = scope
+}
+
+func (c *renderCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.entries)
+	clear(c.flights)
+	c.version++
+}
@@ -0,0 +1,424 @@
+package gotemp_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bllyanos/gotemp"
+)
+
+type countedName string
+
+type privateUser struct{ name string }
+
+func (u privateUser) Greeting() string { return "Hello " + u.name }
+
+type hello struct{ Name string }
+
+func (h hello) Greeting() string { return "Hello " + h.Name }
+
+type goodbye struct{ Name string }
+
+func (g goodbye) Greeting() string { return "Goodbye " + g.Name }
+
+func TestRenderCache(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}Hello {{ .Name }}{{ end }}`,
+	})
+	executions := 0
+	g, err := gotemp.New(dir,
+		gotemp.WithRenderCache(8),
+		gotemp.WithTypeFormatter(countedName(""), func(v any) string {
+			executions++
+			return string(v.(countedName))
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	render := func(data any) string {
+		t.Helper()
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", data); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return buf.String()
+	}
+
+	ada := map[string]any{"Name": countedName("Ada")}
+	render(ada)
+	if out := render(ada); !strings.Contains(out, "Hello Ada") {
+		t.Fatalf("expected rendered greeting, got %q", out)
+	}
+	if executions != 1 {
+		t.Errorf("expected cache hit to skip execution, got %d executions", executions)
+	}
+	if out := render(map[string]any{"Name": countedName("Grace")}); !strings.Contains(out, "Hello Grace") {
+		t.Errorf("expected different data to miss the cache, got %q", out)
+	}
+
+	unhashable := map[string]any{"Name": countedName("Ada"), "Fn": func() {}}
+	render(unhashable)
+	render(unhashable)
+	if executions != 4 {
+		t.Errorf("expected unhashable data to bypass the cache, got %d executions", executions)
+	}
+
+	page := filepath.Join(dir, "pages/home/index.html")
+	if err := os.WriteFile(page, []byte(`{{ define "content" }}Goodbye {{ .Name }}{{ end }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Reload(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out := render(ada); !strings.Contains(out, "Goodbye Ada") {
+		t.Errorf("expected reload to invalidate the cache, got %q", out)
+	}
+}
+
+func TestRenderCacheKeyCollisions(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}{{ .Greeting }}{{ end }}`,
+	}), gotemp.WithRenderCache(8))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, tt := range []struct {
+		data any
+		want string
+	}{
+		{privateUser{name: "Ada"}, "Hello Ada"},
+		{privateUser{name: "Grace"}, "Hello Grace"},
+		{hello{Name: "Ada"}, "Hello Ada"},
+		{goodbye{Name: "Ada"}, "Goodbye Ada"},
+	} {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", tt.data); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if want := "<html><body>" + tt.want + "</body></html>"; buf.String() != want {
+			t.Errorf("expected %q for %#v, got %q", want, tt.data, buf.String())
+		}
+	}
+}
+
+func TestRenderCacheEviction(t *testing.T) {
+	executions := 0
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}Hello {{ . }}{{ end }}`,
+	}),
+		gotemp.WithRenderCache(1),
+		gotemp.WithTypeFormatter(countedName(""), func(v any) string {
+			executions++
+			return string(v.(countedName))
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	for _, name := range []countedName{"Ada", "Grace", "Grace", "Ada"} {
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", name); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if executions != 3 {
+		t.Errorf("expected least recently used entry to be evicted, got %d executions", executions)
+	}
+}
+
+func BenchmarkRenderPage(b *testing.B) {
+	dir := writeTemplates(b, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}<ul>{{ range .Items }}<li class="item">{{ upper .Name }}: {{ .Price }}</li>{{ end }}</ul>{{ end }}`,
+	})
+	type item struct {
+		Name  string
+		Price float64
+	}
+	items := make([]item, 100)
+	for i := range items {
+		items[i] = item{Name: "product", Price: float64(i) * 1.5}
+	}
+	data := map[string]any{"Items": items}
+
+	for _, bench := range []struct {
+		name string
+		opts []gotemp.Option
+	}{
+		{"uncached", nil},
+		{"cached", []gotemp.Option{gotemp.WithRenderCache(64)}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			g, err := gotemp.New(dir, bench.opts...)
+			if err != nil {
+				b.Fatalf("expected no error, got %v", err)
+			}
+			var buf strings.Builder
+			b.ReportAllocs()
+			for b.Loop() {
+				buf.Reset()
+				if err := g.RenderPage(&buf, "app_layout", "home/index.html", data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestPageCacheTTL(t *testing.T) {
+	var executions atomic.Int32
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}Home {{ . }}{{ end }}`,
+		"pages/home/about.html": `{{ define "content" }}About {{ . }}{{ end }}`,
+	}),
+		gotemp.WithRenderCache(8),
+		gotemp.WithCacheTTL(time.Hour),
+		gotemp.WithPageCacheTTL("home/index.html", 20*time.Millisecond),
+		gotemp.WithTypeFormatter(countedName(""), func(v any) string {
+			executions.Add(1)
+			time.Sleep(10 * time.Millisecond)
+			return string(v.(countedName))
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	render := func(page string) {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", page, countedName("Ada")); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}
+
+	render("home/index.html")
+	render("home/about.html")
+	render("home/index.html")
+	render("home/about.html")
+	if n := executions.Load(); n != 2 {
+		t.Fatalf("expected cached renders within the TTL, got %d executions", n)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() { render("home/index.html") })
+	}
+	wg.Wait()
+	if n := executions.Load(); n != 3 {
+		t.Errorf("expected a single regeneration of the expired page, got %d executions", n)
+	}
+
+	render("home/about.html")
+	if n := executions.Load(); n != 3 {
+		t.Errorf("expected the default TTL to keep the other page cached, got %d executions", n)
+	}
+}
+
+func TestPartialCache(t *testing.T) {
+	var executions atomic.Int32
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"partials/nav.html":     `<nav>{{ .Menu }}</nav>`,
+		"pages/home/index.html": `{{ define "content" }}{{ cachedPartial "nav.html" .Nav }}<main>{{ .Body }}</main>{{ end }}`,
+	}),
+		gotemp.WithPartialCache("nav.html", 30*time.Millisecond),
+		gotemp.WithTypeFormatter(countedName(""), func(v any) string {
+			executions.Add(1)
+			return string(v.(countedName))
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	render := func(body string) string {
+		t.Helper()
+		var buf strings.Builder
+		data := map[string]any{"Nav": map[string]any{"Menu": countedName("Home")}, "Body": body}
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", data); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return buf.String()
+	}
+
+	for _, body := range []string{"first", "second", "third"} {
+		if out := render(body); out != "<html><body><nav>Home</nav><main>"+body+"</main></body></html>" {
+			t.Errorf("expected the page around the partial to re-render, got %q", out)
+		}
+	}
+	if n := executions.Load(); n != 1 {
+		t.Errorf("expected the partial to render once within its TTL, got %d", n)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	render("fourth")
+	if n := executions.Load(); n != 2 {
+		t.Errorf("expected the partial to re-render after its TTL, got %d", n)
+	}
+}
+
+func TestWarmCache(t *testing.T) {
+	executions := 0
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html":  `{{ define "content" }}Hello {{ .Name }}{{ end }}`,
+		"pages/home/about.html":  `{{ define "content" }}About {{ .Name }}{{ end }}`,
+		"pages/home/broken.html": `{{ define "content" }}{{ .Name.Missing }}{{ end }}`,
+	}),
+		gotemp.WithRenderCache(8),
+		gotemp.WithTypeFormatter(countedName(""), func(v any) string {
+			executions++
+			return string(v.(countedName))
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	warmed, err := g.WarmCache("app_layout", func(page string) any {
+		return map[string]any{"Name": countedName("Ada")}
+	})
+	if warmed != 2 {
+		t.Errorf("expected 2 warmed pages, got %d", warmed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "warm home/broken.html") {
+		t.Errorf("expected the broken page to be reported, got %v", err)
+	}
+
+	before := executions
+	for _, page := range []string{"home/index.html", "home/about.html"} {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", page, map[string]any{"Name": countedName("Ada")}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !strings.Contains(buf.String(), "Ada") {
+			t.Errorf("expected cached output, got %q", buf.String())
+		}
+	}
+	if executions != before {
+		t.Errorf("expected renders after warming to be cache hits, got %d executions", executions-before)
+	}
+
+	uncached, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := uncached.WarmCache("app_layout", nil); err == nil {
+		t.Error("expected an error without a render cache")
+	}
+}
+
+type fakeCache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	gets   []string
+	sets   []string
+	ttls   []time.Duration
+}
+
+func (c *fakeCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gets = append(c.gets, key)
+	value, ok := c.values[key]
+	return value, ok
+}
+
+func (c *fakeCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sets = append(c.sets, key)
+	c.ttls = append(c.ttls, ttl)
+	c.values[key] = value
+}
+
+func TestRenderCacheBackend(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}Hello {{ .Name }}{{ end }}`,
+	})
+	backend := &fakeCache{values: make(map[string][]byte)}
+	executions := 0
+	newEngine := func() *gotemp.Gotemp {
+		g, err := gotemp.New(dir,
+			gotemp.WithRenderCacheBackend(backend),
+			gotemp.WithCacheTTL(time.Minute),
+			gotemp.WithTypeFormatter(countedName(""), func(v any) string {
+				executions++
+				return string(v.(countedName))
+			}),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return g
+	}
+	render := func(g *gotemp.Gotemp, name string) string {
+		t.Helper()
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", map[string]any{"Name": countedName(name)}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return buf.String()
+	}
+
+	first, second := newEngine(), newEngine()
+	render(first, "Ada")
+	if out := render(second, "Ada"); !strings.Contains(out, "Hello Ada") {
+		t.Fatalf("expected rendered greeting, got %q", out)
+	}
+	if executions != 1 {
+		t.Errorf("expected engines to share the backend, got %d executions", executions)
+	}
+	render(first, "Grace")
+
+	if len(backend.gets) != 3 || len(backend.sets) != 2 {
+		t.Fatalf("expected 3 gets and 2 sets, got gets %v and sets %v", backend.gets, backend.sets)
+	}
+	ada, grace := backend.sets[0], backend.sets[1]
+	if !strings.HasPrefix(ada, "app_layout:home/index.html:") || ada == grace {
+		t.Errorf("expected distinct keys naming the layout and page, got %q and %q", ada, grace)
+	}
+	if backend.gets[0] != ada || backend.gets[1] != ada || backend.gets[2] != grace {
+		t.Errorf("expected gets to use the set keys, got %v", backend.gets)
+	}
+	if backend.ttls[0] != time.Minute {
+		t.Errorf("expected the page TTL to reach the backend, got %v", backend.ttls[0])
+	}
+
+	if err := first.UpdateTemplate("pages/home/index.html", `{{ define "content" }}Bye {{ .Name }}{{ end }}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out := render(first, "Ada"); !strings.Contains(out, "Bye Ada") {
+		t.Errorf("expected the updated template to miss the old backend entries, got %q", out)
+	}
+	deployed, err := gotemp.New(dir, gotemp.WithRenderCacheBackend(backend), gotemp.WithBuildID("v2"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	sets := len(backend.sets)
+	render(deployed, "Ada")
+	if len(backend.sets) != sets+1 {
+		t.Errorf("expected another build ID to miss the shared entries, got sets %v", backend.sets)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	cache := gotemp.NewMemoryCache(1)
+	cache.Set("a", []byte("A"), 0)
+	if value, ok := cache.Get("a"); !ok || string(value) != "A" {
+		t.Errorf("expected stored value, got %q, %v", value, ok)
+	}
+	cache.Set("b", []byte("B"), 0)
+	if _, ok := cache.Get("a"); ok {
+		t.Error("expected the oldest entry to be evicted")
+	}
+	cache.Set("c", []byte("C"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok := cache.Get("c"); ok {
+		t.Error("expected expired entry to miss")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bllyanos/gotemp"
+)
+
+const usage = "usage: gotemp lint [-json] <dir>\n       gotemp serve [-addr :8080] [-layout app_layout] <dir>\n"
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "lint":
+		os.Exit(lint(os.Args[2:], os.Stdout, os.Stderr))
+	case "serve":
+		os.Exit(serve(os.Args[2:], os.Stdout, os.Stderr))
+	default:
+		fmt.Fprintf(os.Stderr, "gotemp: unknown command %q\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+func lint(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var issues []gotemp.LintIssue
+	g, err := gotemp.New(flags.Arg(0), gotemp.WithLazyLoad(true))
+	if err != nil {
+		issues = []gotemp.LintIssue{{Severity: "error", Kind: "load", Message: err.Error()}}
+	} else {
+		issues = g.Lint()
+	}
+
+	errorCount, warningCount := 0, 0
+	for _, issue := range issues {
+		if issue.Severity == "error" {
+			errorCount++
+		} else {
+			warningCount++
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(map[string]any{"issues": issues, "errors": errorCount, "warnings": warningCount})
+	} else {
+		for _, issue := range issues {
+			location := issue.Page
+			if location == "" {
+				location = "-"
+			}
+			fmt.Fprintf(stdout, "%s\t%s\t%s\t%s\n", issue.Severity, issue.Kind, location, issue.Message)
+		}
+		fmt.Fprintf(stdout, "%d error(s), %d warning(s)\n", errorCount, warningCount)
+	}
+	if errorCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+func serve(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	layout := flags.String("layout", "app_layout", "layout to render pages in")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	handler, err := previewHandler(flags.Arg(0), *layout)
+	if err != nil {
+		fmt.Fprintf(stderr, "gotemp: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "previewing %s on %s\n", flags.Arg(0), *addr)
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		fmt.Fprintf(stderr, "gotemp: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>gotemp preview</title></head>
+<body><h1>Pages</h1>
+{{ range . }}<h2>{{ .Dir }}/</h2>
+<ul>{{ range .Pages }}<li><a href="/{{ . }}">{{ . }}</a></li>{{ end }}</ul>
+{{ end }}</body></html>
+`))
+
+type pageDir struct {
+	Dir   string
+	Pages []string
+}
+
+func previewHandler(dir, layout string) (http.Handler, error) {
+	g, err := gotemp.New(dir, gotemp.WithReloadStrategy(gotemp.Checksum), gotemp.WithDefaultLayout(layout))
+	if err != nil {
+		return nil, err
+	}
+	routes := g.Mux(layout, http.Dir(filepath.Join(dir, "static")))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			if err := g.Reload(); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			var dirs []pageDir
+			for _, page := range g.ListPages() {
+				pageDirName := path.Dir(page)
+				if len(dirs) == 0 || dirs[len(dirs)-1].Dir != pageDirName {
+					dirs = append(dirs, pageDir{Dir: pageDirName})
+				}
+				dirs[len(dirs)-1].Pages = append(dirs[len(dirs)-1].Pages, page)
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			indexTemplate.Execute(w, dirs)
+			return
+		}
+		page := strings.TrimPrefix(r.URL.Path, "/")
+		if path.Ext(page) != ".html" {
+			routes.ServeHTTP(w, r)
+			return
+		}
+		var buf bytes.Buffer
+		if err := g.RenderPage(&buf, "", page, nil); errors.Is(err, gotemp.ErrPageNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		buf.WriteTo(w)
+	}), nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := lint([]string{"../../examples"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected exit code 0 for the examples, got %d\n%s%s", code, stdout.String(), stderr.String())
+	}
+	if !strings.HasSuffix(stdout.String(), "0 error(s), 0 warning(s)\n") {
+		t.Errorf("expected a clean summary, got %q", stdout.String())
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"root.html":             `{{ define "__start" }}{{ end }}{{ define "__end" }}{{ end }}`,
+		"layouts/app.html":      `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ template "sidebar" . }}{{ end }}`,
+	} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdout.Reset()
+	if code := lint([]string{"-json", dir}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	var report struct {
+		Issues []struct {
+			Kind     string `json:"kind"`
+			Page     string `json:"page"`
+			Template string `json:"template"`
+		} `json:"issues"`
+		Errors int `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(stdout.String()), &report); err != nil {
+		t.Fatalf("expected JSON output, got %v\n%s", err, stdout.String())
+	}
+	if report.Errors != 1 || len(report.Issues) != 1 || report.Issues[0].Kind != "undefined" || report.Issues[0].Template != "sidebar" {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	stdout.Reset()
+	if code := lint([]string{filepath.Join(dir, "missing")}, &stdout, &stderr); code != 1 || !strings.Contains(stdout.String(), "load") {
+		t.Errorf("expected a load error, got %d %q", code, stdout.String())
+	}
+	if code := lint(nil, &stdout, &stderr); code != 2 {
+		t.Errorf("expected usage exit code 2, got %d", code)
+	}
+}
+
+func TestPreviewHandler(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("root.html", `{{ define "__start" }}{{ end }}{{ define "__end" }}{{ end }}`)
+	write("layouts/app.html", `{{ define "app_layout" }}<main>{{ block "content" . }}{{ end }}</main>{{ end }}`)
+	write("pages/home/index.html", `{{ define "content" }}Home{{ end }}`)
+	write("pages/blog/post.html", `{{ define "content" }}Post{{ end }}`)
+	write("static/app.css", `body {}`)
+
+	handler, err := previewHandler(dir, "app_layout")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	index := get("/").Body.String()
+	for _, want := range []string{"<h2>blog/</h2>", `<a href="/blog/post.html">blog/post.html</a>`, `<a href="/home/index.html">home/index.html</a>`} {
+		if !strings.Contains(index, want) {
+			t.Errorf("expected %s in the index, got %q", want, index)
+		}
+	}
+	if body := get("/blog/post.html").Body.String(); body != "<main>Post</main>" {
+		t.Errorf("expected the page rendered by key, got %q", body)
+	}
+	if body := get("/blog/post").Body.String(); body != "<main>Post</main>" {
+		t.Errorf("expected the page rendered by route, got %q", body)
+	}
+	if body := get("/static/app.css").Body.String(); body != "body {}" {
+		t.Errorf("expected static files, got %q", body)
+	}
+	if rec := get("/blog/missing.html"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing page, got %d", rec.Code)
+	}
+
+	write("pages/blog/new.html", `{{ define "content" }}New{{ end }}`)
+	if !strings.Contains(get("/").Body.String(), "blog/new.html") {
+		t.Error("expected a new page in the index without a restart")
+	}
+	write("pages/blog/post.html", `{{ define "content" }}Edited post{{ end }}`)
+	if body := get("/blog/post.html").Body.String(); body != "<main>Edited post</main>" {
+		t.Errorf("expected the edited page, got %q", body)
+	}
+	write("pages/blog/post.html", `{{ define "content" }}{{ if }}{{ end }}`)
+	if rec := get("/blog/post.html"); rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "post.html") {
+		t.Errorf("expected the parse error in the response, got %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/bllyanos/gotemp"
@@ -13,10 +14,14 @@ func main() {
 		fmt.Printf("Error creating template engine: %v\n", err)
 		return
 	}
-	
-	err = tee.RenderPage(os.Stdout, "app_layout", "home/index.html", nil)
+
+	err = renderHome(os.Stdout, tee)
 	if err != nil {
 		fmt.Printf("Error rendering page: %v\n", err)
 		return
 	}
 }
+
+func renderHome(w io.Writer, renderer gotemp.Renderer) error {
+	return renderer.RenderPage(w, "app_layout", "home/index.html", nil)
+}
@@ -0,0 +1,85 @@
+package gotemp
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template/parse"
+)
+
+func (tc *Gotemp) layoutContracts() (map[string][]string, error) {
+	files, err := tc.layoutFiles()
+	if err != nil {
+		return nil, err
+	}
+	contracts := make(map[string][]string)
+	for _, file := range files {
+		content, err := tc.readTemplate(file)
+		if err != nil {
+			return nil, err
+		}
+		treeSet, err := parseTrees(templateName(file), content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse layout %s: %w", file, err)
+		}
+		blocks := make(map[string]bool)
+		for _, match := range blockAction.FindAllStringSubmatch(content, -1) {
+			blocks[match[1]] = true
+		}
+		for name, tree := range treeSet {
+			if blocks[name] || parse.IsEmptyTree(tree.Root) {
+				continue
+			}
+			var required []string
+			walkNodes(tree.Root, func(node parse.Node) {
+				if node, ok := node.(*parse.TemplateNode); ok && blocks[node.Name] && !slices.Contains(required, node.Name) {
+					required = append(required, node.Name)
+				}
+			})
+			contracts[name] = required
+		}
+	}
+	return contracts, nil
+}
+
+func (tc *Gotemp) checkLayoutContracts(pages map[string]*page) error {
+	contracts, err := tc.layoutContracts()
+	if err != nil {
+		return err
+	}
+	var common []string
+	for i, name := range slices.Sorted(maps.Keys(contracts)) {
+		if i == 0 {
+			common = contracts[name]
+			continue
+		}
+		common = slices.DeleteFunc(slices.Clone(common), func(block string) bool {
+			return !slices.Contains(contracts[name], block)
+		})
+	}
+
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(pages)) {
+		pageEntry := pages[key]
+		required, layout := common, "every layout"
+		if name := pageEntry.frontMatterLayout(tc.defaultLayout); name != "" {
+			required, layout = contracts[name], name
+		}
+		treeSet, err := tc.fileTrees("pages/"+pageEntry.path, templateName(pageEntry.path))
+		if err != nil {
+			continue
+		}
+		var missing []string
+		for _, block := range required {
+			if tree := treeSet[block]; tree == nil || parse.IsEmptyTree(tree.Root) {
+				missing = append(missing, block)
+			}
+		}
+		if len(missing) > 0 {
+			errs = append(errs, fmt.Errorf("%w: page %s does not define %s required by %s", ErrLayoutContract, key, strings.Join(missing, ", "), layout))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,49 @@
+package gotemp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestEnforceLayoutContract(t *testing.T) {
+	files := map[string]string{
+		"layouts/docs.html":        `{{ define "docs_layout" }}{{ block "sidebar" . }}{{ end }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/home/index.html":    `{{ define "content" }}Home{{ end }}`,
+		"pages/home/implicit.html": `Hello`,
+		"pages/home/title.html":    `{{ define "title" }}Title{{ end }}`,
+		"pages/home/empty.html":    `{{ define "content" }}{{ end }}`,
+		"pages/docs/intro.html":    "---\nlayout: docs_layout\n---\n{{ define \"content\" }}Intro{{ end }}",
+		"pages/docs/guide.html":    "---\nlayout: docs_layout\n---\n{{ define \"content\" }}Guide{{ end }}{{ define \"sidebar\" }}Nav{{ end }}",
+	}
+
+	if _, err := gotemp.New(writeTemplates(t, files)); err != nil {
+		t.Fatalf("expected no error without the contract, got %v", err)
+	}
+
+	_, err := gotemp.New(writeTemplates(t, files), gotemp.WithEnforceLayoutContract(true))
+	if !errors.Is(err, gotemp.ErrLayoutContract) {
+		t.Fatalf("expected ErrLayoutContract, got %v", err)
+	}
+	for _, want := range []string{
+		"page docs/intro.html does not define sidebar required by docs_layout",
+		"page home/empty.html does not define content required by every layout",
+		"page home/title.html does not define content required by every layout",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to contain %q, got %v", want, err)
+		}
+	}
+	for _, page := range []string{"home/index.html", "home/implicit.html", "docs/guide.html"} {
+		if strings.Contains(err.Error(), page) {
+			t.Errorf("expected %s to conform, got %v", page, err)
+		}
+	}
+
+	_, err = gotemp.New(writeTemplates(t, files), gotemp.WithEnforceLayoutContract(true), gotemp.WithDefaultLayout("docs_layout"))
+	if err == nil || !strings.Contains(err.Error(), "page home/index.html does not define sidebar required by docs_layout") {
+		t.Errorf("expected pages to follow the default layout's contract, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package gotemp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+type PageLoader func(ctx context.Context) (any, error)
+
+func (tc *Gotemp) RenderPageContext(ctx context.Context, w io.Writer, layout, page string) error {
+	data, err := tc.loadPageData(ctx, page)
+	if err != nil {
+		return err
+	}
+	return tc.RenderPage(w, layout, page, data)
+}
+
+const defaultTruncationMarker = "<!-- content truncated -->"
+
+func (tc *Gotemp) RenderPageStream(ctx context.Context, w io.Writer, layout, page string, data any) error {
+	cw := &contextWriter{ctx: ctx, w: w}
+	err := tc.RenderPage(cw, layout, page, data)
+	if !cw.cancelled {
+		return err
+	}
+	marker := tc.truncationMarker
+	if marker == "" {
+		marker = defaultTruncationMarker
+	}
+	if _, werr := io.WriteString(w, marker); werr != nil {
+		return werr
+	}
+	flush(w)
+	return fmt.Errorf("page %s: output truncated: %w", page, ctx.Err())
+}
+
+type contextWriter struct {
+	ctx       context.Context
+	w         io.Writer
+	cancelled bool
+}
+
+func (cw *contextWriter) Write(p []byte) (int, error) {
+	if err := cw.check(); err != nil {
+		return 0, err
+	}
+	n, err := cw.w.Write(p)
+	flush(cw.w)
+	return n, err
+}
+
+func (cw *contextWriter) check() error {
+	if err := cw.ctx.Err(); err != nil {
+		cw.cancelled = true
+		return err
+	}
+	return nil
+}
+
+type cancelWriter struct {
+	w      io.Writer
+	stream *contextWriter
+}
+
+func (cw *cancelWriter) Write(p []byte) (int, error) {
+	if err := cw.stream.check(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
+
+func flush(w io.Writer) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (tc *Gotemp) loadPageData(ctx context.Context, page string) (any, error) {
+	loader := tc.pageData[page]
+	if loader == nil {
+		return nil, ctx.Err()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	data, err := loader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load data for page %s: %w", page, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (tc *Gotemp) RenderPageJSON(w io.Writer, layout, page, jsonPath string) error {
+	content, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return fmt.Errorf("read data file: %w", err)
+	}
+	var data map[string]any
+	if err := json.Unmarshal(content, &data); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, column := jsonPosition(content, syntaxErr.Offset)
+			return fmt.Errorf("invalid JSON in %s at line %d, column %d: %w", jsonPath, line, column, err)
+		}
+		return fmt.Errorf("invalid JSON in %s: %w", jsonPath, err)
+	}
+	return tc.RenderPage(w, layout, page, data)
+}
+
+func jsonPosition(content []byte, offset int64) (int, int) {
+	before := content[:min(int(offset), len(content))]
+	line := bytes.Count(before, []byte("\n")) + 1
+	return line, max(len(before)-bytes.LastIndexByte(before, '\n')-1, 1)
+}
@@ -0,0 +1,189 @@
+package gotemp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestRenderPageContext(t *testing.T) {
+	errDatabase := errors.New("database unavailable")
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html":  `{{ define "content" }}Hello {{ .Name }}{{ end }}`,
+		"pages/home/slow.html":   `{{ define "content" }}Slow {{ . }}{{ end }}`,
+		"pages/home/broken.html": `{{ define "content" }}Broken{{ end }}`,
+	}),
+		gotemp.WithPageData("home/index.html", func(ctx context.Context) (any, error) {
+			return map[string]string{"Name": "Ada"}, nil
+		}),
+		gotemp.WithPageData("home/slow.html", func(ctx context.Context) (any, error) {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+				return "done", nil
+			}
+		}),
+		gotemp.WithPageData("home/broken.html", func(ctx context.Context) (any, error) {
+			return nil, errDatabase
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPageContext(context.Background(), &buf, "app_layout", "home/index.html"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "Hello Ada") {
+		t.Errorf("expected loaded data in output, got %q", buf.String())
+	}
+
+	buf.Reset()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = g.RenderPageContext(ctx, &buf, "app_layout", "home/slow.html")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("expected the loader to stop at the deadline")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing rendered after a loader error, got %q", buf.String())
+	}
+
+	err = g.RenderPageContext(context.Background(), &buf, "app_layout", "home/broken.html")
+	if !errors.Is(err, errDatabase) || buf.Len() != 0 {
+		t.Errorf("expected loader error without output, got %v and %q", err, buf.String())
+	}
+
+	rec := httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/index", nil))
+	if !strings.Contains(rec.Body.String(), "Hello Ada") {
+		t.Errorf("expected handler to use the page loader, got %q", rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/broken", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 for a loader error, got %d", rec.Code)
+	}
+}
+
+func TestRenderPageJSON(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}{{ .Title }}:{{ range .Items }} {{ .name }}{{ end }}{{ end }}`,
+		"data/home.json":        `{"Title": "Menu", "Items": [{"name": "Tea"}, {"name": "Cake"}]}`,
+		"data/broken.json":      "{\n  \"Title\": \"Menu\",\n  \"Items\": [,]\n}",
+		"data/list.json":        `["Tea"]`,
+	})
+	g, err := gotemp.New(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPageJSON(&buf, "app_layout", "home/index.html", filepath.Join(dir, "data/home.json")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<html><body>Menu: Tea Cake</body></html>" {
+		t.Errorf("expected JSON data in the output, got %q", buf.String())
+	}
+
+	err = g.RenderPageJSON(&buf, "app_layout", "home/index.html", filepath.Join(dir, "data/broken.json"))
+	if err == nil || !strings.Contains(err.Error(), "broken.json at line 3, column 13") {
+		t.Errorf("expected the position of the syntax error, got %v", err)
+	}
+	if err := g.RenderPageJSON(&buf, "app_layout", "home/index.html", filepath.Join(dir, "data/list.json")); err == nil {
+		t.Error("expected an error for JSON that is not an object")
+	}
+	if err := g.RenderPageJSON(&buf, "app_layout", "home/index.html", filepath.Join(dir, "data/missing.json")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist for a missing file, got %v", err)
+	}
+}
+
+func TestRenderPageStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := writeTemplates(t, map[string]string{
+		"layouts/app.html":      `{{ define "app_layout" }}<main>{{ block "content" . }}{{ end }}</main>{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}<p>fast</p>{{ if .Stall }}{{ stall }}{{ end }}<p>slow</p>{{ end }}`,
+	})
+	g, err := gotemp.New(dir,
+		gotemp.WithTruncationMarker(`<p class="truncated">More content is on its way.</p>`),
+		gotemp.WithFuncs(template.FuncMap{"stall": func() string {
+			cancel()
+			return ""
+		}}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	err = g.RenderPageStream(ctx, rec, "app_layout", "home/index.html", map[string]any{"Stall": true})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a context error, got %v", err)
+	}
+	want := `<main><p>fast</p><p class="truncated">More content is on its way.</p>`
+	if rec.Body.String() != want {
+		t.Errorf("expected partial output and the marker, got %q", rec.Body.String())
+	}
+	if !rec.Flushed {
+		t.Error("expected the output to be flushed")
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPageStream(context.Background(), &buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<main><p>fast</p><p>slow</p></main>" {
+		t.Errorf("expected the full page without a marker, got %q", buf.String())
+	}
+
+	cached, err := gotemp.New(dir,
+		gotemp.WithRenderCache(8),
+		gotemp.WithTrimActions(true),
+		gotemp.WithFuncs(template.FuncMap{"stall": func() string {
+			cancel()
+			return ""
+		}}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	rec = httptest.NewRecorder()
+	ctx, cancel = context.WithCancel(context.Background())
+	err = cached.RenderPageStream(ctx, rec, "app_layout", "home/index.html", map[string]any{"Stall": true})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a context error with the render cache, got %v", err)
+	}
+	if rec.Body.String() != "<main><p>fast</p><!-- content truncated -->" {
+		t.Errorf("expected the streamed output to bypass the render cache, got %q", rec.Body.String())
+	}
+	cancel()
+
+	plain, err := gotemp.New(dir, gotemp.WithFuncs(template.FuncMap{"stall": func() string { return "" }}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf.Reset()
+	if err := plain.RenderPageStream(ctx, &buf, "app_layout", "home/index.html", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a context error, got %v", err)
+	}
+	if buf.String() != "<!-- content truncated -->" {
+		t.Errorf("expected the default marker for an already cancelled context, got %q", buf.String())
+	}
+}
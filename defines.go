@@ -0,0 +1,102 @@
+package gotemp
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template/parse"
+)
+
+var blockAction = regexp.MustCompile(`\{\{-?\s*block\s+"([^"]*)"`)
+
+type definition struct {
+	file  string
+	block bool
+}
+
+func (tc *Gotemp) baseDefines(partialFiles, sharedFiles []string) (map[string]definition, error) {
+	layoutFiles, err := tc.layoutFiles()
+	if err != nil {
+		return nil, err
+	}
+	alwaysFiles, err := tc.alwaysFiles()
+	if err != nil {
+		return nil, err
+	}
+	files := append([]string{"root.html"}, alwaysFiles...)
+	for _, file := range partialFiles {
+		if !slices.Contains(alwaysFiles, file) {
+			files = append(files, file)
+		}
+	}
+	files = append(files, sharedFiles...)
+	extraFiles, err := tc.extraDefineFiles()
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, layoutFiles...)
+
+	defines := make(map[string]definition)
+	for _, file := range files {
+		if err := tc.claimDefines(defines, file, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range extraFiles {
+		if slices.Contains(files, file) {
+			continue
+		}
+		content, err := tc.defineSource(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read extra define file %s: %w", file, err)
+		}
+		if err := claimContent(defines, file, file, content, true); err != nil {
+			return nil, err
+		}
+	}
+	return defines, nil
+}
+
+func (tc *Gotemp) claimDefines(defines map[string]definition, file string, claim bool) error {
+	content, err := tc.readTemplate(file)
+	if err != nil {
+		return nil
+	}
+	return claimContent(defines, file, templateName(file), content, claim)
+}
+
+func claimContent(defines map[string]definition, file, name, content string, claim bool) error {
+	treeSet, err := parseTrees(name, content)
+	if err != nil {
+		return nil
+	}
+	blocks := make(map[string]bool)
+	for _, match := range blockAction.FindAllStringSubmatch(content, -1) {
+		blocks[match[1]] = true
+	}
+	for _, name := range slices.Sorted(maps.Keys(treeSet)) {
+		if parse.IsEmptyTree(treeSet[name].Root) {
+			continue
+		}
+		if owner, ok := defines[name]; ok && !owner.block {
+			return fmt.Errorf("template %q is defined in both %s and %s", name, owner.file, file)
+		}
+		if claim {
+			defines[name] = definition{file: file, block: blocks[name]}
+		}
+	}
+	return nil
+}
+
+func templateName(file string) string {
+	if name, ok := strings.CutPrefix(file, "partials/"); ok {
+		return name
+	}
+	if name, ok := strings.CutPrefix(file, "pages/"); ok && isSharedDir(name) {
+		return name
+	}
+	return path.Base(file)
+}
@@ -0,0 +1,52 @@
+package gotemp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestDefinePrecedence(t *testing.T) {
+	files := map[string]string{
+		"root.html":             `{{ define "__start" }}<html><body>{{ end }}{{ define "__end" }}</body></html>{{ end }}{{ define "title" }}Root{{ end }}`,
+		"partials/_title.html":  `{{ define "title" }}Partial{{ end }}{{ define "badge" }}Badge{{ end }}`,
+		"layouts/app.html":      `{{ define "app_layout" }}{{ template "__start" . }}{{ template "title" . }}|{{ template "badge" . }}|{{ block "content" . }}{{ end }}{{ template "__end" . }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+		"pages/home/badge.html": `{{ define "content" }}Page{{ end }}{{ define "badge" }}Page badge{{ end }}`,
+	}
+	g, err := gotemp.New(writeTemplates(t, files))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for page, want := range map[string]string{
+		"home/index.html": "<html><body>Partial|Badge|Home</body></html>",
+		"home/badge.html": "<html><body>Partial|Page badge|Page</body></html>",
+	} {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", page, nil); err != nil {
+			t.Fatalf("%s: expected no error, got %v", page, err)
+		}
+		if buf.String() != want {
+			t.Errorf("%s: expected pages > layouts > partials > root, got %q", page, buf.String())
+		}
+	}
+
+	_, err = gotemp.New(writeTemplates(t, files), gotemp.WithStrictDefines(true))
+	if err == nil || !strings.Contains(err.Error(), `"title" is defined in both root.html and partials/_title.html`) {
+		t.Errorf("expected a conflict between root and partial, got %v", err)
+	}
+
+	delete(files, "root.html")
+	_, err = gotemp.New(writeTemplates(t, files), gotemp.WithStrictDefines(true))
+	if err == nil || !strings.Contains(err.Error(), `"badge" is defined in both partials/_title.html and pages/home/badge.html`) {
+		t.Errorf("expected a conflict between partial and page, got %v", err)
+	}
+
+	delete(files, "pages/home/badge.html")
+	files["layouts/app/_title.html"] = `{{ define "title" }}Scoped{{ end }}`
+	files["pages/home/empty.html"] = `{{ define "content" }}Empty{{ end }}{{ define "badge" }}{{ end }}`
+	if _, err := gotemp.New(writeTemplates(t, files), gotemp.WithStrictDefines(true)); err != nil {
+		t.Errorf("expected block overrides, scoped partials and empty defines to be allowed, got %v", err)
+	}
+}
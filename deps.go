@@ -0,0 +1,202 @@
+package gotemp
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"text/template/parse"
+)
+
+type dependencyGraph struct {
+	dependents map[string][]string
+	defines    map[string][]string
+	embeds     map[string][]string
+	refs       map[string][]string
+	pageRefs   map[string][]string
+}
+
+func (tc *Gotemp) buildGraph(layouts *template.Template, scopes []layoutScope, pages map[string]*page, partialNames map[string]string) (*dependencyGraph, error) {
+	base := make(map[string][]string)
+	baseEmbeds := make(map[string][]string)
+	sets := []*template.Template{layouts}
+	for _, scope := range scopes {
+		sets = append(sets, scope.template)
+	}
+	for _, set := range sets {
+		for _, t := range set.Templates() {
+			if t.Tree != nil {
+				base[t.Name()] = append(base[t.Name()], refNames(treeRefs(t.Tree, partialNames))...)
+				baseEmbeds[t.Name()] = append(baseEmbeds[t.Name()], embedRefs(t.Tree)...)
+			}
+		}
+	}
+
+	layoutFiles, err := tc.layoutFiles()
+	if err != nil {
+		return nil, err
+	}
+	var roots []string
+	for _, file := range layoutFiles {
+		trees, err := tc.fileTrees(file, path.Base(file))
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, slices.Collect(maps.Keys(trees))...)
+	}
+
+	graph := &dependencyGraph{
+		dependents: make(map[string][]string),
+		defines:    make(map[string][]string),
+		embeds:     make(map[string][]string),
+		refs:       base,
+		pageRefs:   make(map[string][]string),
+	}
+	partialFiles, err := tc.partialFiles()
+	if err != nil {
+		return nil, err
+	}
+	var allPartials []string
+	for _, file := range partialFiles {
+		name := strings.TrimPrefix(file, "partials/")
+		trees, err := tc.fileTrees(file, name)
+		if err != nil {
+			return nil, err
+		}
+		graph.defines[name] = slices.Sorted(maps.Keys(trees))
+		allPartials = append(allPartials, graph.defines[name]...)
+	}
+
+	for key, pageEntry := range pages {
+		visited := make(map[string]bool)
+		trees, err := tc.fileTrees(path.Join("pages", pageEntry.path), path.Base(pageEntry.path))
+		if err != nil {
+			for name := range base {
+				visited[name] = true
+			}
+		}
+		own := make(map[string][]string)
+		ownEmbeds := make(map[string][]string)
+		for name, tree := range trees {
+			own[name] = refNames(treeRefs(tree, partialNames))
+			ownEmbeds[name] = embedRefs(tree)
+		}
+		for _, refs := range own {
+			for _, ref := range refs {
+				if _, defined := own[ref]; !defined && !slices.Contains(graph.pageRefs[key], ref) {
+					graph.pageRefs[key] = append(graph.pageRefs[key], ref)
+				}
+			}
+		}
+		graph.embeds[key] = reachable([]string{"content"}, own, base, func(name string) []string {
+			return append(slices.Clone(ownEmbeds[name]), baseEmbeds[name]...)
+		})
+		stack := append(slices.Collect(maps.Keys(own)), roots...)
+		for len(stack) > 0 {
+			name := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if visited[name] {
+				continue
+			}
+			visited[name] = true
+			if name == "" {
+				stack = append(stack, allPartials...)
+			}
+			stack = append(append(stack, own[name]...), base[name]...)
+		}
+		for name := range visited {
+			graph.dependents[name] = append(graph.dependents[name], key)
+		}
+	}
+	for _, dependents := range graph.dependents {
+		slices.Sort(dependents)
+	}
+	return graph, nil
+}
+
+func reachable(start []string, own, base map[string][]string, collect func(name string) []string) []string {
+	visited := make(map[string]bool)
+	var collected []string
+	for stack := slices.Clone(start); len(stack) > 0; {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+		for _, item := range collect(name) {
+			if !slices.Contains(collected, item) {
+				collected = append(collected, item)
+			}
+		}
+		stack = append(append(stack, own[name]...), base[name]...)
+	}
+	return collected
+}
+
+func (tc *Gotemp) fileTrees(file, name string) (map[string]*parse.Tree, error) {
+	content, err := tc.readTemplate(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseTrees(name, content)
+}
+
+func refNames(refs []templateRef) []string {
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = ref.name
+	}
+	return names
+}
+
+func (g *dependencyGraph) pagesUsing(names []string) map[string]bool {
+	pages := make(map[string]bool)
+	for _, name := range names {
+		for _, page := range g.dependents[name] {
+			pages[page] = true
+		}
+	}
+	return pages
+}
+
+func (tc *Gotemp) DependentsOf(name string) []string {
+	graph := tc.set.Load().graph
+	names, ok := graph.defines[name]
+	if !ok {
+		names = []string{name}
+	}
+	return slices.Sorted(maps.Keys(graph.pagesUsing(names)))
+}
+
+func (tc *Gotemp) ReloadPartial(name string) error {
+	tc.reloadMu.Lock()
+	defer tc.reloadMu.Unlock()
+	set := tc.set.Load()
+	names, existed := set.graph.defines[name]
+	trees, err := tc.fileTrees(path.Join("partials", name), name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && !existed:
+		return fmt.Errorf("%w: %s", ErrPartialNotFound, name)
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("failed to parse partial %s: %w", name, err)
+	}
+	names = append(slices.Clone(names), slices.Collect(maps.Keys(trees))...)
+	keep := make(map[string]*page)
+	if !tc.sharedTemplates {
+		affected := set.graph.pagesUsing(names)
+		for key, pageEntry := range set.pages {
+			if !affected[key] {
+				keep[key] = pageEntry
+			}
+		}
+	}
+	if err := tc.loadPagesKeeping(keep); err != nil {
+		return err
+	}
+	return tc.forEngines(func(engine *Gotemp) error { return engine.ReloadPartial(name) })
+}
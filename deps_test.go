@@ -0,0 +1,99 @@
+package gotemp_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestDependentsOf(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html":        `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ template "footer" . }}{{ end }}`,
+		"partials/_footer.html":   `{{ define "footer" }}<footer>{{ template "copyright" . }}</footer>{{ end }}{{ define "copyright" }}(c){{ end }}`,
+		"partials/_widget.html":   `{{ define "widget" }}widget{{ end }}`,
+		"partials/card.html":      `<div class="card">{{ . }}</div>`,
+		"pages/home/index.html":   `{{ define "content" }}{{ template "widget" . }}{{ end }}`,
+		"pages/home/about.html":   `{{ define "content" }}{{ partial "card.html" "About" }}{{ end }}`,
+		"pages/home/dynamic.html": `{{ define "content" }}{{ partial .Name . }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	all := "[home/about.html home/dynamic.html home/index.html]"
+	for name, want := range map[string]string{
+		"_widget.html": "[home/dynamic.html home/index.html]",
+		"widget":       "[home/dynamic.html home/index.html]",
+		"card.html":    "[home/about.html home/dynamic.html]",
+		"_footer.html": all,
+		"copyright":    all,
+		"app_layout":   all,
+		"unknown":      "[]",
+	} {
+		if got := fmt.Sprint(g.DependentsOf(name)); got != want {
+			t.Errorf("DependentsOf(%q): expected %s, got %s", name, want, got)
+		}
+	}
+}
+
+func TestReloadPartial(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"partials/_widget.html": `{{ define "widget" }}widget v1{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ template "widget" . }}{{ end }}`,
+		"pages/home/about.html": `{{ define "content" }}about v1{{ end }}`,
+	})
+	g, err := gotemp.New(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for name, content := range map[string]string{
+		"partials/_widget.html": `{{ define "widget" }}widget v2{{ end }}`,
+		"pages/home/about.html": `{{ define "content" }}about v2{{ end }}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := g.ReloadPartial("_widget.html"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	render := func(page string) string {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", page, nil); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return buf.String()
+	}
+	if out := render("home/index.html"); !strings.Contains(out, "widget v2") {
+		t.Errorf("expected the dependent page to be rebuilt, got %q", out)
+	}
+	if out := render("home/about.html"); !strings.Contains(out, "about v1") {
+		t.Errorf("expected the independent page to be kept, got %q", out)
+	}
+
+	if err := g.Reload(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out := render("home/about.html"); !strings.Contains(out, "about v2") {
+		t.Errorf("expected a full reload to rebuild every page, got %q", out)
+	}
+
+	if err := g.ReloadPartial("missing.html"); !errors.Is(err, gotemp.ErrPartialNotFound) {
+		t.Errorf("expected ErrPartialNotFound, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "partials/_widget.html"), []byte(`{{ define "widget" }}{{ if }}{{ end }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ReloadPartial("_widget.html"); err == nil {
+		t.Error("expected a parse error")
+	}
+	if out := render("home/index.html"); !strings.Contains(out, "widget v2") {
+		t.Errorf("expected the previous templates to stay active after a failed reload, got %q", out)
+	}
+}
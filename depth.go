@@ -0,0 +1,54 @@
+package gotemp
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+type includeDepthError struct {
+	limit int
+	chain []string
+}
+
+func (e *includeDepthError) Error() string {
+	return fmt.Sprintf("%s: more than %d nested includes: %s", ErrIncludeDepth, e.limit, strings.Join(e.chain, " -> "))
+}
+
+func (e *includeDepthError) Unwrap() error {
+	return ErrIncludeDepth
+}
+
+func newRenderState(embeds ...string) *renderState {
+	return &renderState{embeds: embeds, includes: new(int)}
+}
+
+func (tc *Gotemp) include(state *renderState, name string, render func() (template.HTML, error)) (template.HTML, error) {
+	if tc.maxIncludeDepth <= 0 || state.includes == nil {
+		return render()
+	}
+	if *state.includes >= tc.maxIncludeDepth {
+		return "", &includeDepthError{limit: tc.maxIncludeDepth, chain: []string{name}}
+	}
+	*state.includes++
+	defer func() { *state.includes-- }()
+	html, err := render()
+	var depthErr *includeDepthError
+	if errors.As(err, &depthErr) {
+		depthErr.chain = append([]string{name}, depthErr.chain...)
+		return "", depthErr
+	}
+	return html, err
+}
+
+func (tc *Gotemp) renderTemplate(pageEntry *page, layout string, partials map[string]string) (*template.Template, error) {
+	if tc.maxIncludeDepth <= 0 {
+		return pageEntry.lookup(layout), nil
+	}
+	t, err := pageEntry.clone(layout)
+	if err != nil {
+		return nil, err
+	}
+	return tc.bindRender(t, partials, newRenderState(pageEntry.meta.Page)), nil
+}
@@ -0,0 +1,73 @@
+package gotemp_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestMaxIncludeDepth(t *testing.T) {
+	files := map[string]string{
+		"partials/a.html":       `a{{ partial "b.html" . }}`,
+		"partials/b.html":       `b{{ cachedPartial "c.html" . }}`,
+		"partials/c.html":       `c{{ partial "d.html" . }}`,
+		"partials/d.html":       `d`,
+		"partials/loop.html":    `{{ partial "loop.html" . }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ partial "a.html" . }}{{ end }}`,
+		"pages/home/loop.html":  `{{ define "content" }}{{ partial "loop.html" . }}{{ end }}`,
+		"pages/home/embed.html": `{{ define "content" }}{{ renderPage "widgets/a.html" . }}{{ end }}`,
+		"pages/widgets/a.html":  `{{ define "content" }}{{ partial "b.html" . }}{{ end }}`,
+	}
+
+	g, err := gotemp.New(writeTemplates(t, files), gotemp.WithMaxIncludeDepth(4))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected a chain within the limit to render, got %v", err)
+	}
+	if buf.String() != "<html><body>abcd</body></html>" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+
+	g, err = gotemp.New(writeTemplates(t, files), gotemp.WithMaxIncludeDepth(2))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err = g.RenderPage(io.Discard, "app_layout", "home/index.html", nil)
+	if !errors.Is(err, gotemp.ErrIncludeDepth) {
+		t.Fatalf("expected ErrIncludeDepth, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "more than 2 nested includes: a.html -> b.html -> c.html") {
+		t.Errorf("expected the error to name the include chain, got %v", err)
+	}
+
+	err = g.RenderPage(io.Discard, "app_layout", "home/loop.html", nil)
+	if !errors.Is(err, gotemp.ErrIncludeDepth) || !strings.Contains(err.Error(), "loop.html -> loop.html -> loop.html") {
+		t.Errorf("expected a cyclic include to stop at the limit, got %v", err)
+	}
+	if err := g.RenderPartial(io.Discard, "loop.html", nil); !errors.Is(err, gotemp.ErrIncludeDepth) {
+		t.Errorf("expected RenderPartial to enforce the limit, got %v", err)
+	}
+	err = g.RenderPage(io.Discard, "app_layout", "home/embed.html", nil)
+	if !errors.Is(err, gotemp.ErrIncludeDepth) || !strings.Contains(err.Error(), "widgets/a.html -> b.html -> c.html") {
+		t.Errorf("expected renderPage embeds to count towards the limit, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := g.RenderPartial(io.Discard, "c.html", nil); err != nil {
+				t.Errorf("expected concurrent renders to count depth separately, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,62 @@
+package gotemp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+func (tc *Gotemp) ExportGraphDOT(w io.Writer) error {
+	set := tc.set.Load()
+	graph := set.graph
+	contracts, err := tc.layoutContracts()
+	if err != nil {
+		return err
+	}
+	allLayouts := slices.Sorted(maps.Keys(contracts))
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph templates {")
+	fmt.Fprintln(bw, "\trankdir=LR;")
+
+	for i, file := range slices.Sorted(maps.Keys(graph.defines)) {
+		fmt.Fprintf(bw, "\tsubgraph cluster_%d {\n\t\tlabel=%s;\n", i, strconv.Quote("partials/"+file))
+		for _, name := range graph.defines[file] {
+			if name == file && len(graph.defines[file]) > 1 {
+				continue
+			}
+			fmt.Fprintf(bw, "\t\t%s;\n", strconv.Quote(name))
+		}
+		fmt.Fprintln(bw, "\t}")
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(set.pages)) {
+		node := strconv.Quote("page:" + key)
+		fmt.Fprintf(bw, "\t%s [shape=box, label=%s];\n", node, strconv.Quote(key))
+		layouts := allLayouts
+		if layout := set.pages[key].frontMatterLayout(tc.defaultLayout); layout != "" {
+			layouts = []string{layout}
+		}
+		for _, name := range slices.Compact(slices.Sorted(slices.Values(slices.Concat(layouts, graph.pageRefs[key])))) {
+			if name != "" {
+				fmt.Fprintf(bw, "\t%s -> %s;\n", node, strconv.Quote(name))
+			}
+		}
+		for _, embedded := range graph.embeds[key] {
+			fmt.Fprintf(bw, "\t%s -> %s [style=dashed];\n", node, strconv.Quote("page:"+embedded))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(graph.refs)) {
+		refs := slices.Compact(slices.Sorted(slices.Values(graph.refs[name])))
+		for _, ref := range refs {
+			if ref != "" {
+				fmt.Fprintf(bw, "\t%s -> %s;\n", strconv.Quote(name), strconv.Quote(ref))
+			}
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
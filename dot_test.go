@@ -0,0 +1,54 @@
+package gotemp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestExportGraphDOT(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html":      `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ template "footer" . }}{{ end }}`,
+		"partials/_footer.html": `{{ define "footer" }}<footer>{{ template "copyright" . }}</footer>{{ end }}{{ define "copyright" }}(c){{ end }}`,
+		"partials/card.html":    `<div class="card">{{ . }}</div>`,
+		"pages/home/index.html": `{{ define "content" }}{{ partial "card.html" "Home" }}{{ renderPage "home/about.html" . }}{{ end }}`,
+		"pages/home/about.html": "---\nlayout: app_layout\n---\n{{ define \"content\" }}About{{ end }}",
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf strings.Builder
+	if err := g.ExportGraphDOT(&buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "digraph templates {\n") || !strings.HasSuffix(out, "}\n") {
+		t.Errorf("expected a digraph, got %q", out)
+	}
+	for _, want := range []string{
+		`"page:home/index.html" [shape=box, label="home/index.html"];`,
+		`"page:home/index.html" -> "card.html";`,
+		`"page:home/index.html" -> "app_layout";`,
+		`"page:home/index.html" -> "page:home/about.html" [style=dashed];`,
+		`"page:home/about.html" -> "app_layout";`,
+		`"app_layout" -> "content";`,
+		`"app_layout" -> "footer";`,
+		`"footer" -> "copyright";`,
+		`label="partials/_footer.html";`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `"page:home/index.html" -> "content"`) {
+		t.Errorf("expected page defines not to appear as dependencies, got\n%s", out)
+	}
+
+	again := strings.Builder{}
+	g.ExportGraphDOT(&again)
+	if again.String() != out {
+		t.Error("expected deterministic output")
+	}
+}
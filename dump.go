@@ -0,0 +1,34 @@
+package gotemp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+func (tc *Gotemp) DumpPage(page string, w io.Writer) error {
+	if err := tc.reloadIfChanged(); err != nil {
+		return err
+	}
+	pageEntry := tc.set.Load().pages[page]
+	if pageEntry == nil {
+		return fmt.Errorf("%w: %s", ErrPageNotFound, page)
+	}
+	if err := pageEntry.ready(); err != nil {
+		return err
+	}
+	t, err := pageEntry.clone("")
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, name := range pageEntry.templateNames() {
+		def := t.Lookup(pageEntry.entry(t, name))
+		if def == nil || def.Tree == nil || def.Tree.Root == nil {
+			fmt.Fprintf(bw, "{{/* %s: no source */}}\n", name)
+			continue
+		}
+		fmt.Fprintf(bw, "{{ define %q }}%s{{ end }}\n", name, def.Tree.Root)
+	}
+	return bw.Flush()
+}
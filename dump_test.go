@@ -0,0 +1,48 @@
+package gotemp_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestDumpPage(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"partials/card.html":    `{{ define "card" }}<div>{{ . }}</div>{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ template "card" .Name }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	if err := g.DumpPage("home/index.html", &buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, want := range []string{
+		`{{ define "app_layout" }}`,
+		`{{ define "card" }}<div>{{.}}</div>{{ end }}`,
+		`{{ define "content" }}{{template "card" .Name}}{{ end }}`,
+		`{{ define "__start" }}<html><body>{{ end }}`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected the dump to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+
+	if err := g.RenderPage(io.Discard, "app_layout", "home/index.html", map[string]string{"Name": "Ada"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var after strings.Builder
+	if err := g.DumpPage("home/index.html", &after); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if after.String() != buf.String() {
+		t.Errorf("expected the dump to stay free of escapers after a render, got:\n%s", after.String())
+	}
+	if err := g.DumpPage("missing.html", io.Discard); !errors.Is(err, gotemp.ErrPageNotFound) {
+		t.Errorf("expected ErrPageNotFound, got %v", err)
+	}
+}
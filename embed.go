@@ -0,0 +1,126 @@
+package gotemp
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/template/parse"
+)
+
+const embedFunc = "renderPage"
+
+type renderState struct {
+	embeds   []string
+	includes *int
+}
+
+func (tc *Gotemp) renderPageFunc(state *renderState) includeFunc {
+	return tc.profiled(func(page string, data any) (template.HTML, error) {
+		chain := append(slices.Clone(state.embeds), page)
+		if slices.Contains(state.embeds, page) {
+			return "", fmt.Errorf("%s %s: page would embed itself: %s", embedFunc, page, strings.Join(chain, " -> "))
+		}
+		return tc.include(state, page, func() (template.HTML, error) {
+			var buf bytes.Buffer
+			if err := tc.renderEmbedded(&buf, page, data, &renderState{embeds: chain, includes: state.includes}); err != nil {
+				return "", fmt.Errorf("%s %s: %w", embedFunc, page, err)
+			}
+			return template.HTML(buf.String()), nil
+		})
+	})
+}
+
+func (tc *Gotemp) renderEmbedded(w io.Writer, page string, data any, state *renderState) error {
+	if err := tc.reloadIfChanged(); err != nil {
+		return err
+	}
+	set := tc.set.Load()
+	pageEntry := set.pages[page]
+	if pageEntry == nil {
+		return fmt.Errorf("%w: %s", ErrPageNotFound, page)
+	}
+	if err := pageEntry.ready(); err != nil {
+		return err
+	}
+	if !pageEntry.embeds && tc.maxIncludeDepth <= 0 {
+		return tc.RenderBlock(w, "", page, "content", data)
+	}
+	layout := tc.pageLayout(pageEntry, "")
+	data, err := tc.renderData(pageEntry, data)
+	if err != nil {
+		return fmt.Errorf("page %s block content: %w", page, err)
+	}
+	t, err := pageEntry.clone(layout)
+	if err != nil {
+		return err
+	}
+	tc.bindRender(t, set.partials, state)
+	name := pageEntry.entry(t, "content")
+	if t.Lookup(name) == nil {
+		return fmt.Errorf("%w: content in page %s", ErrBlockNotFound, page)
+	}
+	return tc.execute(w, t, name, data, false)
+}
+
+func embedRefs(tree *parse.Tree) []string {
+	var pages []string
+	walkNodes(tree.Root, func(node parse.Node) {
+		command, ok := node.(*parse.CommandNode)
+		if !ok || len(command.Args) < 2 {
+			return
+		}
+		if ident, ok := command.Args[0].(*parse.IdentifierNode); !ok || ident.Ident != embedFunc {
+			return
+		}
+		if page, ok := command.Args[1].(*parse.StringNode); ok {
+			pages = append(pages, page.Text)
+		}
+	})
+	return pages
+}
+
+func (g *dependencyGraph) embedded(page string) []string {
+	var reached []string
+	stack := slices.Clone(g.embeds[page])
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if slices.Contains(reached, next) {
+			continue
+		}
+		reached = append(reached, next)
+		stack = append(stack, g.embeds[next]...)
+	}
+	return reached
+}
+
+func (g *dependencyGraph) embedCycle() error {
+	done := make(map[string]bool)
+	var visit func(chain []string) error
+	visit = func(chain []string) error {
+		page := chain[len(chain)-1]
+		if i := slices.Index(chain, page); i < len(chain)-1 {
+			return fmt.Errorf("%s cycle: %s", embedFunc, strings.Join(chain[i:], " -> "))
+		}
+		if done[page] {
+			return nil
+		}
+		for _, next := range g.embeds[page] {
+			if err := visit(append(slices.Clone(chain), next)); err != nil {
+				return err
+			}
+		}
+		done[page] = true
+		return nil
+	}
+	for _, page := range slices.Sorted(maps.Keys(g.embeds)) {
+		if err := visit([]string{page}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package gotemp_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestRenderPageFunc(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"partials/_time.html":      `{{ define "time" }}<time>{{ .Now }}</time>{{ end }}`,
+		"pages/widgets/clock.html": `{{ define "content" }}<div class="clock">{{ template "time" . }}</div>{{ end }}`,
+		"pages/home/index.html":    `{{ define "content" }}<main>{{ renderPage "widgets/clock.html" . }}</main>{{ end }}`,
+		"pages/home/dynamic.html":  `{{ define "content" }}{{ renderPage .Embed . }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", map[string]any{"Now": "12:00"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := `<html><body><main><div class="clock"><time>12:00</time></div></main></body></html>`; buf.String() != want {
+		t.Errorf("expected the embedded page without its layout, got %q", buf.String())
+	}
+
+	buf.Reset()
+	err = g.RenderPage(&buf, "app_layout", "home/dynamic.html", map[string]any{"Embed": "home/dynamic.html"})
+	if err == nil || !strings.Contains(err.Error(), "would embed itself") {
+		t.Errorf("expected the recursion guard for a dynamic self-reference, got %v", err)
+	}
+	err = g.RenderPage(&buf, "app_layout", "home/dynamic.html", map[string]any{"Embed": "widgets/missing.html"})
+	if err == nil || !strings.Contains(err.Error(), "page template not found") {
+		t.Errorf("expected ErrPageNotFound for a missing embed, got %v", err)
+	}
+
+	for name, files := range map[string]map[string]string{
+		"self": {
+			"pages/widgets/loop.html": `{{ define "content" }}{{ renderPage "widgets/loop.html" . }}{{ end }}`,
+		},
+		"indirect": {
+			"pages/widgets/a.html": `{{ define "content" }}{{ template "b" . }}{{ end }}`,
+			"partials/_b.html":     `{{ define "b" }}{{ renderPage "widgets/b.html" . }}{{ end }}`,
+			"pages/widgets/b.html": `{{ define "content" }}{{ renderPage "widgets/a.html" . }}{{ end }}`,
+		},
+	} {
+		_, err := gotemp.New(writeTemplates(t, files))
+		if err == nil || !strings.Contains(err.Error(), "renderPage cycle") {
+			t.Errorf("%s: expected the cycle to fail the load, got %v", name, err)
+		}
+	}
+}
+
+func TestRenderPageFuncDataCycle(t *testing.T) {
+	files := map[string]string{
+		"pages/x/a.html": `{{ define "content" }}a{{ renderPage .P1 . }}{{ end }}`,
+		"pages/x/b.html": `{{ define "content" }}b{{ renderPage .P2 . }}{{ end }}`,
+	}
+	data := map[string]any{"P1": "x/b.html", "P2": "x/a.html"}
+	for name, opts := range map[string][]gotemp.Option{
+		"per page": nil,
+		"shared":   {gotemp.WithSharedTemplates(true)},
+	} {
+		g, err := gotemp.New(writeTemplates(t, files), opts...)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		err = g.RenderPage(io.Discard, "app_layout", "x/a.html", data)
+		if err == nil || !strings.Contains(err.Error(), "would embed itself") {
+			t.Errorf("%s: expected the data-driven cycle to fail the render, got %v", name, err)
+		}
+	}
+}
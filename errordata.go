@@ -0,0 +1,62 @@
+package gotemp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const errorDataLimit = 512
+
+func (tc *Gotemp) withDataContext(err error, data any) error {
+	if !tc.errorDataContext {
+		return err
+	}
+	return fmt.Errorf("%w (data: %s)", err, tc.dumpData(data))
+}
+
+func (tc *Gotemp) dumpData(data any) string {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Sprintf("%T, not JSON encodable", data)
+	}
+	var decoded any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return fmt.Sprintf("%T, not JSON encodable", data)
+	}
+	encoded, err = json.Marshal(tc.redact(decoded))
+	if err != nil {
+		return fmt.Sprintf("%T, not JSON encodable", data)
+	}
+	if len(encoded) > errorDataLimit {
+		return string(encoded[:errorDataLimit]) + "...(truncated)"
+	}
+	return string(encoded)
+}
+
+func (tc *Gotemp) redact(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if tc.redacted(key) {
+				value[key] = "[REDACTED]"
+			} else {
+				value[key] = tc.redact(field)
+			}
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = tc.redact(item)
+		}
+	}
+	return value
+}
+
+func (tc *Gotemp) redacted(key string) bool {
+	for _, redacted := range tc.redactKeys {
+		if strings.EqualFold(key, redacted) {
+			return true
+		}
+	}
+	return false
+}
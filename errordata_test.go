@@ -0,0 +1,62 @@
+package gotemp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestErrorDataContext(t *testing.T) {
+	files := map[string]string{
+		"pages/home/index.html": `{{ define "content" }}{{ .User.Name.First }}{{ end }}`,
+	}
+	data := map[string]any{
+		"User":  map[string]any{"Name": "Ada", "Password": "hunter2"},
+		"Token": "abc123",
+		"Items": []any{map[string]any{"api_key": "k-1"}},
+	}
+
+	g, err := gotemp.New(writeTemplates(t, files))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	err = g.RenderPage(&buf, "app_layout", "home/index.html", data)
+	if err == nil || strings.Contains(err.Error(), "data:") {
+		t.Errorf("expected the plain template error by default, got %v", err)
+	}
+
+	g, err = gotemp.New(writeTemplates(t, files),
+		gotemp.WithErrorDataContext(true), gotemp.WithRedactKeys("password", "token", "API_KEY"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err = g.RenderPage(&buf, "app_layout", "home/index.html", data)
+	if err == nil {
+		t.Fatal("expected an execution error")
+	}
+	msg := err.Error()
+	for _, want := range []string{`"Name":"Ada"`, `"Password":"[REDACTED]"`, `"Token":"[REDACTED]"`, `"api_key":"[REDACTED]"`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %s in the error, got %v", want, msg)
+		}
+	}
+	for _, secret := range []string{"hunter2", "abc123", "k-1"} {
+		if strings.Contains(msg, secret) {
+			t.Errorf("expected %q to be redacted, got %v", secret, msg)
+		}
+	}
+	if data["Token"] != "abc123" {
+		t.Error("expected the caller's data to be left untouched")
+	}
+
+	err = g.RenderPage(&buf, "app_layout", "home/index.html", map[string]any{"User": strings.Repeat("x", 2000)})
+	if err == nil || !strings.Contains(err.Error(), "...(truncated)") || len(err.Error()) > 1000 {
+		t.Errorf("expected a truncated data dump, got %d bytes", len(err.Error()))
+	}
+	err = g.RenderPage(&buf, "app_layout", "home/index.html", map[string]any{"User": func() {}})
+	if err == nil || !strings.Contains(err.Error(), "not JSON encodable") {
+		t.Errorf("expected a note for data that cannot be encoded, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package gotemp
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+)
+
+func (tc *Gotemp) RenderPageExcluding(w io.Writer, layout, page string, data any, excludePartials []string) error {
+	if err := tc.reloadIfChanged(); err != nil {
+		return err
+	}
+	set := tc.set.Load()
+	pageEntry := set.pages[page]
+	if pageEntry == nil {
+		return fmt.Errorf("%w: %s", ErrPageNotFound, page)
+	}
+	if err := pageEntry.ready(); err != nil {
+		return err
+	}
+	layout = tc.pageLayout(pageEntry, layout)
+	data, err := tc.renderData(pageEntry, data)
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	t, err := pageEntry.clone(layout)
+	if err != nil {
+		return err
+	}
+	for _, name := range excludePartials {
+		entrypoint, ok := set.partials[name]
+		if !ok {
+			entrypoint = name
+		}
+		if t.Lookup(entrypoint) == nil {
+			return fmt.Errorf("%w: %s", ErrPartialNotFound, name)
+		}
+		if _, err := t.New(entrypoint).Parse(`{{ "" }}`); err != nil {
+			return fmt.Errorf("failed to exclude partial %s: %w", name, err)
+		}
+	}
+	state := newRenderState(pageEntry.meta.Page)
+	render := tc.partialFunc(t, set.partials, state)
+	tc.bindRender(t, set.partials, state).Funcs(template.FuncMap{
+		"cachedPartial": tc.profiled(func(name string, data any) (template.HTML, error) {
+			if slices.Contains(excludePartials, name) {
+				return "", nil
+			}
+			return render(name, data)
+		}),
+	})
+
+	err = tc.execute(w, t, pageEntry.entry(t, layout), data, true)
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package gotemp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestRenderPageExcluding(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"partials/_chat.html":    `{{ define "chat" }}<script src="/chat.js"></script>{{ end }}`,
+		"partials/_nav.html":     `{{ define "nav" }}<nav>Nav</nav>{{ end }}`,
+		"partials/carousel.html": `<div class="carousel">{{ . }}</div>`,
+		"layouts/app.html":       `{{ define "app_layout" }}{{ template "nav" . }}{{ block "content" . }}{{ end }}{{ template "chat" . }}{{ end }}`,
+		"pages/home/index.html":  `{{ define "content" }}<main>{{ partial "carousel.html" "slides" }}</main>{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf strings.Builder
+	if err := g.RenderPageExcluding(&buf, "app_layout", "home/index.html", nil, []string{"chat", "carousel.html"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<nav>Nav</nav><main></main>" {
+		t.Errorf("expected excluded partials to render empty, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := `<nav>Nav</nav><main><div class="carousel">slides</div></main><script src="/chat.js"></script>`
+	if buf.String() != want {
+		t.Errorf("expected regular render to keep every partial, got %q", buf.String())
+	}
+
+	if err := g.RenderPageExcluding(&buf, "app_layout", "home/index.html", nil, []string{"missing"}); !errors.Is(err, gotemp.ErrPartialNotFound) {
+		t.Errorf("expected ErrPartialNotFound for an unknown partial, got %v", err)
+	}
+
+	g, err = gotemp.New(writeTemplates(t, map[string]string{
+		"partials/_widget.html": `{{ define "widget" }}<b>Widget</b>{{ end }}`,
+		"partials/menu.html":    `<nav>{{ template "widget" . }}</nav>`,
+		"pages/home/index.html": `{{ define "content" }}{{ cachedPartial "menu.html" nil }}{{ end }}`,
+	}), gotemp.WithPartialCache("menu.html", time.Hour))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf.Reset()
+	if err := g.RenderPageExcluding(&buf, "app_layout", "home/index.html", nil, []string{"widget"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "<html><body><nav></nav></body></html>"; buf.String() != want {
+		t.Errorf("expected the excluded define to render empty, got %q", buf.String())
+	}
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "<html><body><nav><b>Widget</b></nav></body></html>"; buf.String() != want {
+		t.Errorf("expected the excluded render to stay out of the partial cache, got %q", buf.String())
+	}
+}
@@ -0,0 +1,46 @@
+package gotemp
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"text/template/parse"
+)
+
+func (tc *Gotemp) ExecuteWith(w io.Writer, t *template.Template, layout string, data any) error {
+	if err := tc.reloadIfChanged(); err != nil {
+		return err
+	}
+	set := tc.set.Load()
+	own, err := t.Clone()
+	if err != nil {
+		return fmt.Errorf("failed to clone caller template: %w", err)
+	}
+	own.Option(tc.parseOptions()...).Funcs(tc.funcs())
+
+	layouts := set.layouts
+	for _, scope := range set.scopes {
+		if slices.Contains(scope.layouts, layout) {
+			layouts = scope.template
+		}
+	}
+	for _, lt := range layouts.Templates() {
+		if lt.Tree == nil {
+			continue
+		}
+		if existing := own.Lookup(lt.Name()); existing != nil && existing.Tree != nil && !parse.IsEmptyTree(existing.Tree.Root) {
+			continue
+		}
+		if _, err := own.AddParseTree(lt.Name(), lt.Tree.Copy()); err != nil {
+			return fmt.Errorf("failed to add layout template %s: %w", lt.Name(), err)
+		}
+	}
+
+	t = tc.bindRender(tc.bind(own, set.partials), set.partials, newRenderState())
+	err = tc.execute(w, t, layout, data, true)
+	if err != nil {
+		return fmt.Errorf("layout %s: %w", layout, err)
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package gotemp_test
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestExecuteWith(t *testing.T) {
+	g, err := gotemp.New("testdata/scoped")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	custom := template.Must(template.New("custom").Delims("[[", "]]").Funcs(template.FuncMap{
+		"shout": func(s string) string { return s + "!" },
+	}).Parse(`[[ define "content" ]]<p>[[ shout . ]]</p>[[ end ]]`))
+
+	var buf bytes.Buffer
+	if err := g.ExecuteWith(&buf, custom, "app_layout", "<Ada>"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "<p>&lt;Ada&gt;!</p>") || !strings.Contains(out, "Global footer") {
+		t.Errorf("expected caller content inside the layout, got %q", out)
+	}
+
+	buf.Reset()
+	if err := g.ExecuteWith(&buf, custom, "marketing_layout", "Ada"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "<p>Ada!</p>") || !strings.Contains(buf.String(), "Marketing footer") {
+		t.Errorf("expected scoped partials for the marketing layout, got %q", buf.String())
+	}
+
+	if err := custom.ExecuteTemplate(&buf, "content", "Ada"); err != nil {
+		t.Fatalf("expected caller template to stay usable, got %v", err)
+	}
+	if err := g.ExecuteWith(&buf, custom, "app_layout", "Ada"); err == nil {
+		t.Error("expected error for an already executed template")
+	}
+	if err := g.ExecuteWith(&buf, template.New("empty"), "nonexistent_layout", nil); err == nil {
+		t.Error("expected error for non-existent layout")
+	}
+}
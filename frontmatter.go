@@ -0,0 +1,267 @@
+package gotemp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+type PageMeta struct {
+	Page        string
+	Route       string
+	Title       string
+	Description string
+	Date        time.Time
+	Fields      map[string]string
+}
+
+type Directives map[string]bool
+
+func (d Directives) Has(name string) bool {
+	return d[name]
+}
+
+func parseDirectives(value string) Directives {
+	directives := make(Directives)
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		directives[name] = true
+	}
+	return directives
+}
+
+var frontMatterDateLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", time.DateOnly}
+
+var frontMatterDelimiters = map[string]string{"---": "---", "{{/*": "*/}}"}
+
+func splitFrontMatter(content string) (map[string]string, string, error) {
+	lines := strings.SplitAfter(content, "\n")
+	closing, ok := frontMatterDelimiters[strings.TrimSpace(lines[0])]
+	if !ok {
+		return nil, content, nil
+	}
+	fields := make(map[string]string)
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == closing {
+			return fields, strings.Repeat("\n", i+1) + strings.Join(lines[i+1:], ""), nil
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if key = strings.TrimSpace(key); !ok || key == "" {
+			return nil, "", fmt.Errorf("front matter line %d: expected key: value, got %q", i+1, line)
+		}
+		fields[key] = strings.TrimSpace(value)
+	}
+	return nil, "", fmt.Errorf("front matter is not closed by %s", closing)
+}
+
+func isQuoted(value string) bool {
+	return len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0]
+}
+
+func unquoteValue(value string) string {
+	if !isQuoted(value) {
+		return value
+	}
+	if value[0] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+	return value[1 : len(value)-1]
+}
+
+func typedValue(value string) any {
+	if isQuoted(value) {
+		return unquoteValue(value)
+	}
+	if value == "true" || value == "false" {
+		return value == "true"
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+func (tc *Gotemp) readTemplate(file string) (string, error) {
+	content, err := fs.ReadFile(tc.fsys, file)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(file, "pages/") {
+		return string(content), nil
+	}
+	_, body, err := splitFrontMatter(string(content))
+	if err != nil || isSharedDir(path.Base(path.Dir(file))) {
+		return body, err
+	}
+	return implicitContent(file, body), nil
+}
+
+func (tc *Gotemp) pageMeta(pageEntry *page, file, key string) error {
+	pageEntry.meta = PageMeta{Page: key, Route: pageRoute(pageEntry.path)}
+	content, err := fs.ReadFile(tc.fsys, file)
+	if err != nil {
+		return err
+	}
+	fields, _, err := splitFrontMatter(string(content))
+	if err != nil || fields == nil {
+		return err
+	}
+	meta := &pageEntry.meta
+	meta.Fields = make(map[string]string, len(fields))
+	pageEntry.values = make(map[string]any, len(fields))
+	for key, value := range fields {
+		meta.Fields[key] = unquoteValue(value)
+		pageEntry.values[key] = typedValue(value)
+	}
+	meta.Title, meta.Description = meta.Fields["title"], meta.Fields["description"]
+	if date := meta.Fields["date"]; date != "" {
+		for _, layout := range frontMatterDateLayouts {
+			if meta.Date, err = time.Parse(layout, date); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("invalid front matter date %q", date)
+		}
+		pageEntry.values["date"] = meta.Date
+	}
+	pageEntry.cache = meta.Fields["cache"]
+	if maxAge := meta.Fields["max-age"]; maxAge != "" {
+		if n, err := strconv.Atoi(maxAge); err != nil || n < 0 {
+			return fmt.Errorf("invalid front matter max-age %q", maxAge)
+		}
+		pageEntry.cache = strings.TrimPrefix(pageEntry.cache+", max-age="+maxAge, ", ")
+	}
+	return nil
+}
+
+func (tc *Gotemp) Meta(page string) (map[string]any, bool) {
+	pageEntry := tc.set.Load().pages[page]
+	if pageEntry == nil || pageEntry.values == nil {
+		return nil, false
+	}
+	return maps.Clone(pageEntry.values), true
+}
+
+func (tc *Gotemp) pageLayout(pageEntry *page, layout string) string {
+	if layout == "" {
+		layout = tc.defaultLayout
+	}
+	return pageEntry.frontMatterLayout(layout)
+}
+
+func (p *page) frontMatterLayout(fallback string) string {
+	if layout, ok := p.values["layout"].(string); ok && layout != "" {
+		return layout
+	}
+	return fallback
+}
+
+func (tc *Gotemp) PagesMeta() []PageMeta {
+	pages := tc.set.Load().pages
+	metas := make([]PageMeta, 0, len(pages))
+	for _, name := range tc.ListPages() {
+		meta := pages[name].meta
+		meta.Fields = maps.Clone(meta.Fields)
+		metas = append(metas, meta)
+	}
+	sort.SliceStable(metas, func(i, j int) bool { return metas[i].Date.After(metas[j].Date) })
+	return metas
+}
+
+func (tc *Gotemp) RenderText(w io.Writer, name string, data any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("text template %s: %w", name, tc.recovered(r))
+		}
+	}()
+	if err := tc.reloadIfChanged(); err != nil {
+		return err
+	}
+	t, err := tc.textTemplate(name)
+	if err != nil {
+		return err
+	}
+	if tc.lineNumbers {
+		w = &lineNumberWriter{w: w, start: true}
+	}
+	if err := t.Execute(w, data); err != nil {
+		return tc.withDataContext(err, data)
+	}
+	return nil
+}
+
+type lineNumberWriter struct {
+	w     io.Writer
+	line  int
+	start bool
+}
+
+func (lw *lineNumberWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if lw.start {
+			lw.line++
+			if _, err := fmt.Fprintf(lw.w, "%4d | ", lw.line); err != nil {
+				return written, err
+			}
+			lw.start = false
+		}
+		end := len(p)
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			end, lw.start = i+1, true
+		}
+		n, err := lw.w.Write(p[:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[end:]
+	}
+	return written, nil
+}
+
+func (tc *Gotemp) textTemplate(name string) (*texttemplate.Template, error) {
+	if cached, ok := tc.textCache.Load(name); ok {
+		return cached.(*texttemplate.Template), nil
+	}
+	content, err := fs.ReadFile(tc.fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text template %s: %w", name, err)
+	}
+	funcs := stringFuncs()
+	maps.Copy(funcs, tc.customFuncMap())
+	funcs["pages"], funcs["xml"] = tc.PagesMeta, xmlEscape
+	if tc.baseData != nil {
+		funcs["site"] = tc.site
+	}
+	t, err := texttemplate.New(path.Base(name)).Option(tc.parseOptions()...).Funcs(texttemplate.FuncMap(funcs)).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse text template %s: %w", name, err)
+	}
+	tc.textCache.Store(name, t)
+	return t, nil
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,237 @@
+package gotemp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestPagesMeta(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/blog/first.html":  "---\ntitle: First & Foremost\ndate: 2024-01-02\n---\n{{ define \"content\" }}First{{ end }}",
+		"pages/blog/second.html": "---\ntitle: \"Second: the sequel\"\ndescription: More\ndate: 2024-03-04T10:00:00Z\nauthor: Ada\n---\n{{ define \"content\" }}Second{{ end }}",
+		"pages/blog/index.html":  `{{ define "content" }}Index{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	metas := g.PagesMeta()
+	if len(metas) != 3 || metas[0].Page != "blog/second.html" || metas[1].Page != "blog/first.html" || metas[2].Page != "blog/index.html" {
+		t.Fatalf("expected pages sorted newest first, got %+v", metas)
+	}
+	second := metas[0]
+	if second.Title != "Second: the sequel" || second.Description != "More" || second.Route != "/blog/second" || second.Fields["author"] != "Ada" {
+		t.Errorf("unexpected metadata %+v", second)
+	}
+	if !second.Date.Equal(time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected parsed date, got %v", second.Date)
+	}
+
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "blog/first.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<html><body>First</body></html>" {
+		t.Errorf("expected front matter to be stripped, got %q", buf.String())
+	}
+}
+
+func TestPagesMetaInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"unclosed": "---\ntitle: Draft\n{{ define \"content\" }}{{ end }}",
+		"no colon": "---\ntitle\n---\n{{ define \"content\" }}{{ end }}",
+		"bad date": "---\ndate: yesterday\n---\n{{ define \"content\" }}{{ end }}",
+	} {
+		_, err := gotemp.New(writeTemplates(t, map[string]string{"pages/blog/draft.html": content}))
+		if err == nil || !strings.Contains(err.Error(), "pages/blog/draft.html") {
+			t.Errorf("%s: expected front matter error naming the page, got %v", name, err)
+		}
+	}
+
+	_, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/blog/broken.html": "---\ntitle: Broken\n---\n{{ define \"content\" }}{{ if }}{{ end }}",
+	}))
+	if err == nil || !strings.Contains(err.Error(), "broken.html:4") {
+		t.Errorf("expected parse error line numbers to include the front matter, got %v", err)
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/blog/first.html": "---\ntitle: Fish & Chips\ndate: 2024-01-02\n---\n{{ define \"content\" }}First{{ end }}",
+		"feeds/rss.xml": `<rss version="2.0"><channel><title>{{ .Title | xml }}</title>` +
+			`{{ range pages }}{{ if .Title }}<item><title>{{ .Title | xml }}</title><link>{{ $.BaseURL }}{{ .Route }}</link>` +
+			`<pubDate>{{ .Date.Format "Mon, 02 Jan 2006 15:04:05 -0700" }}</pubDate></item>{{ end }}{{ end }}</channel></rss>`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf strings.Builder
+	if err := g.RenderText(&buf, "feeds/rss.xml", map[string]any{"Title": "Blog <dev>", "BaseURL": "https://example.com"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := `<rss version="2.0"><channel><title>Blog &lt;dev&gt;</title><item><title>Fish &amp; Chips</title>` +
+		`<link>https://example.com/blog/first</link><pubDate>Tue, 02 Jan 2024 00:00:00 +0000</pubDate></item></channel></rss>`
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	if err := g.RenderText(&buf, "feeds/missing.xml", nil); err == nil {
+		t.Error("expected error for a missing text template")
+	}
+}
+
+func TestMeta(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/wide.html":     `{{ define "wide_layout" }}<div class="wide">{{ block "content" . }}{{ end }}</div>{{ end }}`,
+		"pages/blog/post.html":  "---\ntitle: Post\nlayout: wide_layout\ndraft: false\nviews: 42\nrating: 4.5\nversion: \"2\"\n---\n{{ define \"content\" }}{{ .Meta.title }} {{ if .Meta.draft }}draft{{ else }}live{{ end }} {{ .Name }}{{ end }}",
+		"pages/blog/about.html": "{{/*\ntitle: About\n*/}}\n{{ define \"content\" }}{{ .Meta.title }}{{ end }}",
+		"pages/blog/plain.html": `{{ define "content" }}plain{{ end }}`,
+	}), gotemp.WithDefaultLayout("app_layout"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	meta, ok := g.Meta("blog/post.html")
+	if !ok || meta["title"] != "Post" || meta["draft"] != false || meta["views"] != 42 || meta["rating"] != 4.5 || meta["version"] != "2" {
+		t.Errorf("unexpected metadata %#v", meta)
+	}
+	if _, ok := g.Meta("blog/plain.html"); ok {
+		t.Error("expected no metadata for a page without front matter")
+	}
+	if _, ok := g.Meta("blog/missing.html"); ok {
+		t.Error("expected no metadata for a missing page")
+	}
+
+	for _, test := range []struct {
+		layout, page string
+		data         any
+		want         string
+	}{
+		{"", "blog/post.html", map[string]any{"Name": "Ada"}, `<div class="wide">Post live Ada</div>`},
+		{"app_layout", "blog/post.html", map[string]any{"Name": "Ada"}, `<div class="wide">Post live Ada</div>`},
+		{"", "blog/about.html", nil, `<html><body>About</body></html>`},
+		{"", "blog/plain.html", nil, `<html><body>plain</body></html>`},
+	} {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, test.layout, test.page, test.data); err != nil {
+			t.Fatalf("%s: expected no error, got %v", test.page, err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("%s with layout %q: expected %q, got %q", test.page, test.layout, test.want, buf.String())
+		}
+	}
+}
+
+func TestDirectives(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html": `{{ define "app_layout" }}{{ block "content" . }}{{ end }}` +
+			`{{ if not (.Directives.Has "noAnalytics") }}<script src="/analytics.js"></script>{{ end }}` +
+			`{{ if not (.Directives.Has "noChat") }}<script src="/chat.js"></script>{{ end }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+		"pages/home/login.html": "---\ndirectives: noAnalytics, noChat\n---\n{{ define \"content\" }}Login{{ end }}",
+		"pages/home/error.html": "---\ntitle: Error\ndirectives: noAnalytics\n---\n{{ define \"content\" }}Error{{ end }}",
+	}), gotemp.WithMissingKey(gotemp.MissingKeyError))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for page, want := range map[string]string{
+		"home/index.html": `Home<script src="/analytics.js"></script><script src="/chat.js"></script>`,
+		"home/login.html": `Login`,
+		"home/error.html": `Error<script src="/chat.js"></script>`,
+	} {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", page, map[string]any{"User": "Ada"}); err != nil {
+			t.Fatalf("%s: expected no error, got %v", page, err)
+		}
+		if buf.String() != want {
+			t.Errorf("%s: expected %q, got %q", page, want, buf.String())
+		}
+	}
+}
+
+func TestDrafts(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layouts/app.html":      `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/blog/draft.html": "---\ntitle: Upcoming\ndraft: true\n---\nUpcoming",
+		"pages/blog/live.html":  "---\ntitle: Live\ndraft: false\n---\nLive",
+	})
+
+	prod, err := gotemp.New(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if pages := prod.ListPages(); len(pages) != 1 || pages[0] != "blog/live.html" {
+		t.Errorf("expected only the published page to be listed, got %v", pages)
+	}
+	if metas := prod.PagesMeta(); len(metas) != 1 || metas[0].Title != "Live" {
+		t.Errorf("expected drafts to be left out of PagesMeta, got %+v", metas)
+	}
+	var buf strings.Builder
+	if err := prod.RenderPage(&buf, "app_layout", "blog/draft.html", nil); !errors.Is(err, gotemp.ErrPageNotFound) {
+		t.Errorf("expected ErrPageNotFound for a draft, got %v", err)
+	}
+
+	dev, err := gotemp.New(dir, gotemp.WithIncludeDrafts(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if pages := dev.ListPages(); len(pages) != 2 {
+		t.Errorf("expected drafts to be listed in dev, got %v", pages)
+	}
+	buf.Reset()
+	if err := dev.RenderPage(&buf, "app_layout", "blog/draft.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "Upcoming" {
+		t.Errorf("expected draft to render in dev, got %q", buf.String())
+	}
+}
+
+func TestLineNumbers(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layouts/app.html":      `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/home/index.html": "{{ define \"content\" }}a\nb{{ end }}",
+		"emails/welcome.txt":    "Hello {{ .Name }},\n\n{{ range .Lines }}{{ . }}\n{{ end }}Bye",
+	})
+	data := map[string]any{"Name": "Ada", "Lines": []string{"one", "two"}}
+
+	g, err := gotemp.New(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	if err := g.RenderText(&buf, "emails/welcome.txt", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "Hello Ada,\n\none\ntwo\nBye" {
+		t.Errorf("expected no line numbers by default, got %q", buf.String())
+	}
+
+	g, err = gotemp.New(dir, gotemp.WithLineNumbers(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf.Reset()
+	if err := g.RenderText(&buf, "emails/welcome.txt", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "   1 | Hello Ada,\n   2 | \n   3 | one\n   4 | two\n   5 | Bye"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "a\nb" {
+		t.Errorf("expected HTML renders to be unnumbered, got %q", buf.String())
+	}
+}
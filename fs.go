@@ -0,0 +1,286 @@
+package gotemp
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type overlayFS struct {
+	upper fs.FS
+	lower fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	file, err := o.upper.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.lower.Open(name)
+	}
+	return file, err
+}
+
+func (o overlayFS) ReadFile(name string) ([]byte, error) {
+	content, err := fs.ReadFile(o.upper, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fs.ReadFile(o.lower, name)
+	}
+	return content, err
+}
+
+func (o overlayFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := fs.Stat(o.upper, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fs.Stat(o.lower, name)
+	}
+	return info, err
+}
+
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, upperErr := fs.ReadDir(o.upper, name)
+	if upperErr != nil && !errors.Is(upperErr, fs.ErrNotExist) {
+		return nil, upperErr
+	}
+	lower, lowerErr := fs.ReadDir(o.lower, name)
+	if lowerErr != nil && !errors.Is(lowerErr, fs.ErrNotExist) {
+		return nil, lowerErr
+	}
+	if upperErr != nil && lowerErr != nil {
+		return nil, upperErr
+	}
+
+	merged := make(map[string]fs.DirEntry)
+	for _, entry := range lower {
+		merged[entry.Name()] = entry
+	}
+	for _, entry := range upper {
+		merged[entry.Name()] = entry
+	}
+	entries := make([]fs.DirEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+type cachingFS struct {
+	fsys    fs.FS
+	files   sync.Map
+	infos   sync.Map
+	entries sync.Map
+}
+
+type cachedResult[T any] struct {
+	value T
+	err   error
+}
+
+func (c *cachingFS) reset() {
+	c.files.Clear()
+	c.infos.Clear()
+	c.entries.Clear()
+}
+
+func (c *cachingFS) Open(name string) (fs.File, error) {
+	return c.fsys.Open(name)
+}
+
+func (c *cachingFS) ReadFile(name string) ([]byte, error) {
+	if cached, ok := c.files.Load(name); ok {
+		result := cached.(cachedResult[[]byte])
+		return result.value, result.err
+	}
+	content, err := fs.ReadFile(c.fsys, name)
+	c.files.Store(name, cachedResult[[]byte]{content, err})
+	return content, err
+}
+
+func (c *cachingFS) Stat(name string) (fs.FileInfo, error) {
+	if cached, ok := c.infos.Load(name); ok {
+		result := cached.(cachedResult[fs.FileInfo])
+		return result.value, result.err
+	}
+	info, err := fs.Stat(c.fsys, name)
+	c.infos.Store(name, cachedResult[fs.FileInfo]{info, err})
+	return info, err
+}
+
+func (c *cachingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if cached, ok := c.entries.Load(name); ok {
+		result := cached.(cachedResult[[]fs.DirEntry])
+		return result.value, result.err
+	}
+	entries, err := fs.ReadDir(c.fsys, name)
+	c.entries.Store(name, cachedResult[[]fs.DirEntry]{entries, err})
+	return entries, err
+}
+
+type sourceFS map[string]string
+
+func (s sourceFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if content, ok := s[name]; ok {
+		return &sourceFile{info: sourceInfo{name: path.Base(name), size: int64(len(content))}, Reader: strings.NewReader(content)}, nil
+	}
+	entries, err := s.ReadDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &sourceDir{info: sourceInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+}
+
+func (s sourceFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	children := make(map[string]sourceInfo)
+	for file, content := range s {
+		rest, ok := strings.CutPrefix(file, prefix)
+		if !ok {
+			continue
+		}
+		if child, _, isDir := strings.Cut(rest, "/"); isDir {
+			children[child] = sourceInfo{name: child, dir: true}
+		} else {
+			children[child] = sourceInfo{name: child, size: int64(len(content))}
+		}
+	}
+	if len(children) == 0 {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, info := range children {
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+type sourceInfo struct {
+	name    string
+	size    int64
+	dir     bool
+	modTime time.Time
+}
+
+func (i sourceInfo) Name() string       { return i.name }
+func (i sourceInfo) Size() int64        { return i.size }
+func (i sourceInfo) ModTime() time.Time { return i.modTime }
+func (i sourceInfo) IsDir() bool        { return i.dir }
+func (i sourceInfo) Sys() any           { return nil }
+
+func (i sourceInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+type sourceFile struct {
+	info sourceInfo
+	*strings.Reader
+}
+
+func (f *sourceFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *sourceFile) Close() error               { return nil }
+
+type sourceDir struct {
+	info    sourceInfo
+	entries []fs.DirEntry
+}
+
+func (d *sourceDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *sourceDir) Close() error               { return nil }
+
+func (d *sourceDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *sourceDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+type editFS struct {
+	mu       sync.RWMutex
+	files    sourceFS
+	modTimes map[string]time.Time
+}
+
+func (e *editFS) set(name, content string) (previous string, existed bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.files == nil {
+		e.files = make(sourceFS)
+		e.modTimes = make(map[string]time.Time)
+	}
+	previous, existed = e.files[name]
+	e.files[name] = content
+	e.modTimes[name] = time.Now()
+	return previous, existed
+}
+
+func (e *editFS) restore(name, previous string, existed bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if existed {
+		e.files[name] = previous
+	} else {
+		delete(e.files, name)
+		delete(e.modTimes, name)
+	}
+}
+
+func (e *editFS) Open(name string) (fs.File, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	file, err := e.files.Open(name)
+	if source, ok := file.(*sourceFile); ok {
+		source.info.modTime = e.modTimes[name]
+	}
+	return file, err
+}
+
+func (e *editFS) ReadFile(name string) ([]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	content, ok := e.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return []byte(content), nil
+}
+
+func (e *editFS) Stat(name string) (fs.FileInfo, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	content, ok := e.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return sourceInfo{name: path.Base(name), size: int64(len(content)), modTime: e.modTimes[name]}, nil
+}
+
+func (e *editFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.files.ReadDir(name)
+}
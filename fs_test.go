@@ -0,0 +1,222 @@
+package gotemp_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestOverlayFS(t *testing.T) {
+	base, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tenant, err := base.OverlayFS(fstest.MapFS{
+		"partials/_header.html":  {Data: []byte(`{{ define "_header" }}<h1>Tenant Brand</h1>{{ end }}`)},
+		"pages/promo/index.html": {Data: []byte(`{{ define "content" }}Tenant promo{{ end }}`)},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tenant.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "Tenant Brand") {
+		t.Error("expected tenant header override in output")
+	}
+	if !strings.Contains(buf.String(), "Homepage") {
+		t.Error("expected base page to be used when tenant has no override")
+	}
+
+	buf.Reset()
+	if err := tenant.RenderPage(&buf, "auth_layout", "promo/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "Tenant promo") {
+		t.Error("expected tenant-only page to be renderable")
+	}
+
+	buf.Reset()
+	if err := base.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "Your APP!!") || strings.Contains(buf.String(), "Tenant Brand") {
+		t.Error("expected base engine to be unaffected by the tenant overlay")
+	}
+	if err := base.RenderPage(&buf, "app_layout", "promo/index.html", nil); err == nil {
+		t.Error("expected tenant-only page not to exist in the base engine")
+	}
+}
+
+func TestOverlayFSKeepsOptions(t *testing.T) {
+	base, err := gotemp.New("examples", gotemp.WithTrimActions(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	tenant, err := base.OverlayFS(fstest.MapFS{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tenant.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("\n\n")) {
+		t.Error("expected base options to apply to the overlay engine")
+	}
+}
+
+func TestOverlayFSDropsEnvAndPolling(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layouts/app.html":                  `{{ define "app_layout" }}{{ template "banner" . }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"partials/_banner.html":             `{{ define "banner" }}{{ end }}`,
+		"pages/home/index.html":             `{{ define "content" }}Home{{ end }}`,
+		"env/staging/partials/_banner.html": `{{ define "banner" }}Staging {{ end }}`,
+	})
+	base, err := gotemp.New(dir, gotemp.WithEnv("staging"), gotemp.WithPollReload(time.Hour))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer base.Close()
+
+	before := runtime.NumGoroutine()
+	tenant, err := base.OverlayFS(fstest.MapFS{
+		"partials/_banner.html": {Data: []byte(`{{ define "banner" }}Tenant {{ end }}`)},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("expected the overlay not to start a poller, goroutines went from %d to %d", before, after)
+	}
+
+	var buf bytes.Buffer
+	if err := tenant.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "Tenant Home" {
+		t.Errorf("expected the tenant file to win over the base env override, got %q", buf.String())
+	}
+	buf.Reset()
+	if err := base.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "Staging Home" {
+		t.Errorf("expected the base to keep its env override, got %q", buf.String())
+	}
+}
+
+func TestNewFromSources(t *testing.T) {
+	sources := map[string]string{
+		"root.html":             `{{ define "__start" }}<html><body>{{ end }}{{ define "__end" }}</body></html>{{ end }}`,
+		"partials/_header.html": `{{ define "header" }}<h1>Compiled</h1>{{ end }}`,
+		"layouts/app.html":      `{{ define "app_layout" }}{{ template "__start" . }}{{ template "header" . }}{{ block "content" . }}{{ end }}{{ template "__end" . }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}Hello {{ . }}{{ end }}`,
+	}
+	g, err := gotemp.NewFromSources(sources)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", "Ada"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<html><body><h1>Compiled</h1>Hello Ada</body></html>" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestEnv(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layouts/app.html":                  `{{ define "app_layout" }}{{ template "banner" . }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"partials/_banner.html":             `{{ define "banner" }}{{ end }}`,
+		"pages/home/index.html":             `{{ define "content" }}Home{{ end }}`,
+		"env/staging/partials/_banner.html": `{{ define "banner" }}<div class="banner">Staging</div>{{ end }}`,
+		"env/staging/pages/home/debug.html": `{{ define "content" }}Debug{{ end }}`,
+	})
+
+	for env, want := range map[string]string{
+		"":           "Home",
+		"production": "Home",
+		"staging":    `<div class="banner">Staging</div>Home`,
+	} {
+		g, err := gotemp.New(dir, gotemp.WithEnv(env))
+		if err != nil {
+			t.Fatalf("%q: expected no error, got %v", env, err)
+		}
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+			t.Fatalf("%q: expected no error, got %v", env, err)
+		}
+		if buf.String() != want {
+			t.Errorf("%q: expected %q, got %q", env, want, buf.String())
+		}
+		if hasDebug := slices.Contains(g.ListPages(), "home/debug.html"); hasDebug != (env == "staging") {
+			t.Errorf("%q: unexpected env-only page listing %v", env, g.ListPages())
+		}
+	}
+
+	if _, err := gotemp.New(dir, gotemp.WithEnv("../staging")); err == nil {
+		t.Error("expected an error for an environment name outside env/")
+	}
+}
+
+func TestOverlayFSSeesBaseUpdates(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"partials/_footer.html": `{{ define "footer" }}old footer{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ template "footer" }}{{ end }}`,
+	})
+	base, err := gotemp.New(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	tenant, err := base.OverlayFS(fstest.MapFS{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := base.UpdateTemplate("partials/_footer.html", `{{ define "footer" }}new footer{{ end }}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := tenant.Reload(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tenant.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "new footer") {
+		t.Errorf("expected the overlay to pick up the base update, got %q", buf.String())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "pages", "home", "index.html"), []byte(`{{ define "content" }}edited on disk{{ end }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := base.Reload(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	tenant, err = base.OverlayFS(fstest.MapFS{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf.Reset()
+	if err := tenant.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "edited on disk") {
+		t.Errorf("expected overlays built after a base reload to read the new files, got %q", buf.String())
+	}
+}
@@ -0,0 +1,437 @@
+package gotemp
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func (tc *Gotemp) funcs() template.FuncMap {
+	funcs := stringFuncs()
+	maps.Copy(funcs, tc.customFuncMap())
+	maps.Copy(funcs, tc.builtinFuncs())
+	return funcs
+}
+
+func (tc *Gotemp) customFuncMap() template.FuncMap {
+	funcs := template.FuncMap{}
+	if custom := tc.customFuncs.Load(); custom != nil {
+		maps.Copy(funcs, *custom)
+	}
+	return funcs
+}
+
+func (tc *Gotemp) builtinFuncs() template.FuncMap {
+	funcs := template.FuncMap{
+		"partial": func(name string, data any) (template.HTML, error) {
+			return "", fmt.Errorf("partial %s: template set is not bound", name)
+		},
+		"cachedPartial": func(name string, data any) (template.HTML, error) {
+			return "", fmt.Errorf("cachedPartial %s: template set is not bound", name)
+		},
+		embedFunc: func(page string, data any) (template.HTML, error) {
+			return "", fmt.Errorf("%s %s: template set is not bound", embedFunc, page)
+		},
+		"raw":      tc.raw,
+		formatFunc: tc.format,
+		auditFunc:  tc.audit,
+		listFunc:   listItems,
+	}
+	for name, fn := range assetHelpers() {
+		funcs[name] = fn
+	}
+	if tc.requestHelpers {
+		for name, fn := range requestFuncs(nil) {
+			funcs[name] = fn
+		}
+	}
+	if tc.flags != nil {
+		funcs["flag"] = tc.flagFunc(nil)
+	}
+	if tc.roles != nil {
+		funcs["hasRole"] = tc.roleFunc(nil)
+	}
+	if tc.baseData != nil {
+		funcs["site"] = tc.site
+	}
+	if tc.remote != nil {
+		funcs["includeURL"] = tc.remote.include
+	}
+	return funcs
+}
+
+func (tc *Gotemp) SetFuncs(funcs template.FuncMap) error {
+	if err := tc.checkFuncs(funcs); err != nil {
+		return err
+	}
+	custom := maps.Clone(funcs)
+
+	tc.reloadMu.Lock()
+	defer tc.reloadMu.Unlock()
+	set := tc.set.Load()
+	previous := tc.customFuncs.Swap(&custom)
+	var removed []string
+	if previous != nil {
+		for name := range *previous {
+			if _, ok := custom[name]; !ok {
+				removed = append(removed, name)
+			}
+		}
+	}
+	if err := tc.rebindFuncs(removed); err != nil {
+		tc.customFuncs.Store(previous)
+		tc.textCache.Clear()
+		return fmt.Errorf("set funcs: %w", err)
+	}
+	if err := tc.forEngines(func(engine *Gotemp) error { return engine.SetFuncs(custom) }); err != nil {
+		restore := template.FuncMap{}
+		if previous != nil {
+			restore = *previous
+		}
+		tc.customFuncs.Store(previous)
+		tc.set.Store(set)
+		tc.clearCaches()
+		tc.forEngines(func(engine *Gotemp) error { return engine.SetFuncs(restore) })
+		return fmt.Errorf("set funcs: %w", err)
+	}
+	return nil
+}
+
+func (tc *Gotemp) rebindFuncs(removed []string) error {
+	removed = slices.DeleteFunc(removed, func(name string) bool {
+		_, ok := stringFuncs()[name]
+		return ok
+	})
+	check := func(t *template.Template) error {
+		for _, name := range removed {
+			if usesIdentifier(t, name) {
+				return fmt.Errorf("template: %s: function %q not defined", t.Name(), name)
+			}
+		}
+		return nil
+	}
+
+	set := tc.set.Load()
+	layouts, err := clone(set.layouts)
+	if err != nil {
+		return fmt.Errorf("failed to clone layout template: %w", err)
+	}
+	if err := check(layouts.Funcs(tc.funcs())); err != nil {
+		return err
+	}
+	scopes := make([]layoutScope, len(set.scopes))
+	for i, scope := range set.scopes {
+		scoped, err := clone(scope.template)
+		if err != nil {
+			return fmt.Errorf("failed to clone scoped layout template: %w", err)
+		}
+		if err := check(scoped.Funcs(tc.funcs())); err != nil {
+			return err
+		}
+		scopes[i] = layoutScope{layouts: scope.layouts, template: scoped}
+	}
+
+	pages := make(map[string]*page, len(set.pages))
+	for key, old := range set.pages {
+		pageEntry := &page{
+			path:      old.path,
+			namespace: old.namespace,
+			files:     old.files,
+			modTime:   old.modTime,
+			meta:      old.meta,
+			values:    old.values,
+			defaults:  old.defaults,
+			cache:     old.cache,
+			source:    old.source,
+		}
+		if !tc.sharedTemplates {
+			name := path.Join("pages", old.path)
+			pageEntry.compile = func() error {
+				if err := old.ready(); err != nil {
+					return err
+				}
+				if err := tc.compileSource(pageEntry, name, old.source, layouts, scopes, set.partials); err != nil {
+					return err
+				}
+				for _, t := range append(slices.Collect(maps.Values(pageEntry.scoped)), pageEntry.template) {
+					if err := check(t); err != nil {
+						return fmt.Errorf("failed to parse page template %s: %w", name, err)
+					}
+				}
+				return nil
+			}
+			if !tc.lazyLoad {
+				if err := pageEntry.ready(); err != nil {
+					return err
+				}
+			}
+		}
+		pages[key] = pageEntry
+	}
+
+	var base *template.Template
+	if tc.sharedTemplates {
+		base, err = tc.linkShared(pages, slices.Sorted(maps.Keys(pages)), layouts, scopes, set.partials)
+		if err != nil {
+			return err
+		}
+		if err := check(base); err != nil {
+			return err
+		}
+		for _, pageEntry := range pages {
+			for _, t := range pageEntry.scoped {
+				if err := check(t); err != nil {
+					return err
+				}
+			}
+		}
+	} else if base, err = clone(layouts); err != nil {
+		return fmt.Errorf("failed to clone layout template: %w", err)
+	}
+
+	tc.set.Store(&templateSet{
+		base:     tc.bind(base, set.partials),
+		graph:    set.graph,
+		layouts:  layouts,
+		scopes:   scopes,
+		pages:    pages,
+		partials: set.partials,
+		bad:      set.bad,
+	})
+	tc.clearCaches()
+	return nil
+}
+
+func (tc *Gotemp) checkFuncs(funcs template.FuncMap) (err error) {
+	builtins := tc.builtinFuncs()
+	for name := range funcs {
+		if _, ok := builtins[name]; ok {
+			return fmt.Errorf("func %s is reserved by gotemp", name)
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid funcs: %v", r)
+		}
+	}()
+	template.New("funcs").Funcs(funcs)
+	return nil
+}
+
+func stringFuncs() template.FuncMap {
+	return template.FuncMap{
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+		"trunc":      trunc,
+		"sortedKeys": sortedKeys,
+		"sortedMap":  sortedMap,
+		"jsonld":     jsonld,
+	}
+}
+
+func title(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		words[i] = string(unicode.ToUpper(r)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+func join(sep string, list any) (string, error) {
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: expected a list, got %T", list)
+	}
+	parts := make([]string, value.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(value.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func trunc(length int, s string) string {
+	runes := []rune(s)
+	if length < 0 || len(runes) <= length {
+		return s
+	}
+	return string(runes[:length])
+}
+
+type mapEntry struct {
+	Key   any
+	Value any
+}
+
+func sortedKeys(m any) (any, error) {
+	value := reflect.ValueOf(m)
+	if value.Kind() != reflect.Map {
+		return nil, fmt.Errorf("sortedKeys: expected a map, got %T", m)
+	}
+	keys := value.MapKeys()
+	slices.SortFunc(keys, compareKeys)
+	sorted := reflect.MakeSlice(reflect.SliceOf(value.Type().Key()), len(keys), len(keys))
+	for i, key := range keys {
+		sorted.Index(i).Set(key)
+	}
+	return sorted.Interface(), nil
+}
+
+func sortedMap(m any) ([]mapEntry, error) {
+	value := reflect.ValueOf(m)
+	if value.Kind() != reflect.Map {
+		return nil, fmt.Errorf("sortedMap: expected a map, got %T", m)
+	}
+	keys := value.MapKeys()
+	slices.SortFunc(keys, compareKeys)
+	entries := make([]mapEntry, len(keys))
+	for i, key := range keys {
+		entries[i] = mapEntry{Key: key.Interface(), Value: value.MapIndex(key).Interface()}
+	}
+	return entries, nil
+}
+
+func compareKeys(a, b reflect.Value) int {
+	for a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+	if a.Kind() == b.Kind() {
+		switch a.Kind() {
+		case reflect.String:
+			return cmp.Compare(a.String(), b.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return cmp.Compare(a.Int(), b.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return cmp.Compare(a.Uint(), b.Uint())
+		case reflect.Float32, reflect.Float64:
+			return cmp.Compare(a.Float(), b.Float())
+		case reflect.Bool:
+			return cmp.Compare(fmt.Sprint(a.Bool()), fmt.Sprint(b.Bool()))
+		}
+	}
+	return cmp.Compare(fmt.Sprintf("%s %v", a.Type(), a), fmt.Sprintf("%s %v", b.Type(), b))
+}
+
+func jsonld(v any) (template.JS, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("jsonld: %w", err)
+	}
+	return template.JS(encoded), nil
+}
+
+const formatFunc = "_gotemp_format"
+
+func (tc *Gotemp) bind(t *template.Template, partials map[string]string) *template.Template {
+	if len(tc.formatters) > 0 {
+		appendToActions(t, formatFunc)
+	}
+	if tc.escapeDebug {
+		appendCommand(t, auditFunc, auditLocation)
+	}
+	if usesIdentifier(t, assetFuncs...) {
+		tc.assets.Store(true)
+	}
+	return tc.bindRender(t, partials, &renderState{})
+}
+
+func (tc *Gotemp) bindRender(t *template.Template, partials map[string]string, state *renderState) *template.Template {
+	return t.Funcs(template.FuncMap{
+		"partial":       tc.profiled(tc.partialFunc(t, partials, state)),
+		"cachedPartial": tc.profiled(tc.cachedPartialFunc(t, partials, state)),
+		embedFunc:       tc.renderPageFunc(state),
+	})
+}
+
+func (tc *Gotemp) site() map[string]any {
+	return tc.baseData
+}
+
+func (tc *Gotemp) format(value any) any {
+	if format, ok := tc.formatters[reflect.TypeOf(value)]; ok {
+		return format(value)
+	}
+	return value
+}
+
+func (tc *Gotemp) raw(name string) (template.HTML, error) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	watched := tc.reloadStrategy == Checksum || tc.pollInterval > 0
+	if cached, ok := tc.rawCache.Load(name); ok && !watched {
+		return cached.(template.HTML), nil
+	}
+	content, err := fs.ReadFile(tc.fsys, name)
+	if err != nil {
+		return "", fmt.Errorf("raw %s: %w", name, err)
+	}
+	html := template.HTML(content)
+	if !watched {
+		tc.rawCache.Store(name, html)
+	}
+	return html, nil
+}
+
+func (tc *Gotemp) cachedPartialFunc(t *template.Template, partials map[string]string, state *renderState) func(name string, data any) (template.HTML, error) {
+	render := tc.partialFunc(t, partials, state)
+	return func(name string, data any) (template.HTML, error) {
+		ttl, ok := tc.partialCacheTTL[name]
+		if !ok || tc.perRequest() {
+			return render(name, data)
+		}
+		var buf bytes.Buffer
+		err := tc.partialCache.render(&buf, "", name, data, ttl, func(w io.Writer) error {
+			html, err := render(name, data)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, string(html))
+			return err
+		})
+		return template.HTML(buf.String()), err
+	}
+}
+
+func (tc *Gotemp) partialFunc(t *template.Template, partials map[string]string, state *renderState) func(name string, data any) (template.HTML, error) {
+	return func(name string, data any) (template.HTML, error) {
+		entrypoint, ok := partials[name]
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrPartialNotFound, name)
+		}
+		return tc.include(state, name, func() (template.HTML, error) {
+			return includeTemplate(t, entrypoint, data)
+		})
+	}
+}
+
+func includeTemplate(t *template.Template, entrypoint string, data any) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, entrypoint, data); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
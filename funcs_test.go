@@ -0,0 +1,322 @@
+package gotemp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestStringFuncs(t *testing.T) {
+	cases := []struct {
+		template string
+		data     any
+		expected string
+	}{
+		{`{{ trim . }}`, "  padded  ", "padded"},
+		{`{{ trimPrefix "go" . }}`, "gotemp", "temp"},
+		{`{{ trimSuffix ".html" . }}`, "index.html", "index"},
+		{`{{ upper . }}`, "shout", "SHOUT"},
+		{`{{ lower . }}`, "WHISPER", "whisper"},
+		{`{{ title . }}`, "hello  template world", "Hello Template World"},
+		{`{{ replace "-" " " . }}`, "a-b-c", "a b c"},
+		{`{{ if contains "temp" . }}yes{{ end }}`, "gotemp", "yes"},
+		{`{{ if hasPrefix "go" . }}yes{{ end }}`, "gotemp", "yes"},
+		{`{{ if hasSuffix "temp" . }}yes{{ end }}`, "gotemp", "yes"},
+		{`{{ range split "," . }}[{{ . }}]{{ end }}`, "a,b,c", "[a][b][c]"},
+		{`{{ join ", " . }}`, []string{"a", "b", "c"}, "a, b, c"},
+		{`{{ join "-" . }}`, []any{1, "two", 3}, "1-two-3"},
+		{`{{ repeat 3 . }}`, "ab", "ababab"},
+		{`{{ trunc 3 . }}`, "gotemp", "got"},
+		{`{{ trunc 10 . }}`, "gotemp", "gotemp"},
+		{`{{ . | lower | replace " " "-" }}`, "My Blog Post", "my-blog-post"},
+	}
+
+	files := map[string]string{}
+	for i, c := range cases {
+		files[fmt.Sprintf("partials/case%d.html", i)] = c.template
+	}
+	g, err := gotemp.New(writeTemplates(t, files), gotemp.WithOptionalPages(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for i, c := range cases {
+		var buf bytes.Buffer
+		if err := g.RenderPartial(&buf, fmt.Sprintf("case%d.html", i), c.data); err != nil {
+			t.Errorf("%s: expected no error, got %v", c.template, err)
+			continue
+		}
+		if buf.String() != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.template, c.expected, buf.String())
+		}
+	}
+}
+
+func TestJoinRejectsNonList(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"partials/join.html": `{{ join "," . }}`,
+	}), gotemp.WithOptionalPages(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPartial(&buf, "join.html", 42); err == nil {
+		t.Error("expected error joining a non-list value")
+	}
+}
+
+type money int64
+
+func (m money) cents() int64 { return int64(m) }
+
+func formatMoney(value any) string {
+	cents := value.(money).cents()
+	dollars := fmt.Sprintf("%d", cents/100)
+	for i := len(dollars) - 3; i > 0; i -= 3 {
+		dollars = dollars[:i] + "," + dollars[i:]
+	}
+	return fmt.Sprintf("$%s.%02d", dollars, cents%100)
+}
+
+func TestTypeFormatter(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"partials/price.html":  `<p>{{ .Price }}</p><ul>{{ range .History }}<li>{{ . }}</li>{{ end }}</ul><span>{{ .Count }}</span>`,
+		"partials/raw.html":    `{{ .Price | html }}`,
+		"pages/shop/item.html": `{{ define "content" }}<b>{{ .Price }}</b>{{ end }}`,
+	}), gotemp.WithTypeFormatter(money(0), formatMoney))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := map[string]any{
+		"Price":   money(123456),
+		"History": []money{100, 99999},
+		"Count":   3,
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPartial(&buf, "price.html", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := `<p>$1,234.56</p><ul><li>$1.00</li><li>$999.99</li></ul><span>3</span>`
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "shop/item.html", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("<b>$1,234.56</b>")) {
+		t.Errorf("expected formatted price in page output, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.RenderPartial(&buf, "raw.html", data); err != nil {
+		t.Fatalf("expected pipelines ending in html to be left alone, got %v", err)
+	}
+}
+
+func TestTypeFormatterOutputIsEscaped(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"partials/price.html": `<p>{{ . }}</p>`,
+	}), gotemp.WithOptionalPages(true), gotemp.WithTypeFormatter(money(0), func(any) string {
+		return "<script>"
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPartial(&buf, "price.html", money(1)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<p>&lt;script&gt;</p>" {
+		t.Errorf("expected escaped formatter output, got %q", buf.String())
+	}
+}
+
+func TestRawInclude(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"assets/icon.svg":       `<svg viewBox="0 0 1 1"><path d="M0 0"/></svg>`,
+		"partials/icon.html":    `<i>{{ raw "assets/icon.svg" }}</i>`,
+		"partials/bad.html":     `{{ raw "../outside.svg" }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ raw "/assets/icon.svg" }}{{ end }}`,
+	})
+	g, err := gotemp.New(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPartial(&buf, "icon.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := `<i><svg viewBox="0 0 1 1"><path d="M0 0"/></svg></i>`
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "assets", "icon.svg"), []byte("<svg>changed</svg>"), 0o644); err != nil {
+		t.Fatalf("failed to update asset: %v", err)
+	}
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `<path d="M0 0"/>`) {
+		t.Errorf("expected cached asset contents, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.RenderPartial(&buf, "bad.html", nil); err == nil {
+		t.Error("expected error reading a file outside the template directory")
+	}
+
+	g, err = gotemp.New(dir, gotemp.WithReloadStrategy(gotemp.Checksum))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.RenderPartial(io.Discard, "icon.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "assets", "icon.svg"), []byte("<svg>edited</svg>"), 0o644); err != nil {
+		t.Fatalf("failed to update asset: %v", err)
+	}
+	buf.Reset()
+	if err := g.RenderPartial(&buf, "icon.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<i><svg>edited</svg></i>" {
+		t.Errorf("expected the Checksum strategy to read the asset again, got %q", buf.String())
+	}
+}
+
+func TestSetFuncs(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}{{ greet .Name }}{{ end }}`,
+		"feeds/hello.txt":       `{{ greet .Name }}`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithFuncs(template.FuncMap{
+		"greet": func(name string) string { return "Hello, " + name },
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	render := func() string {
+		t.Helper()
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", map[string]any{"Name": "Ada"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := g.RenderText(&buf, "feeds/hello.txt", map[string]any{"Name": "Ada"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return buf.String()
+	}
+	if out := render(); out != "<html><body>Hello, Ada</body></html>Hello, Ada" {
+		t.Errorf("expected the initial func, got %q", out)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "pages/home/index.html"), []byte(`{{ define "content" }}edited{{ end }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetFuncs(template.FuncMap{"greet": func(name string) string { return "Welcome back, " + name }}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out := render(); out != "<html><body>Welcome back, Ada</body></html>Welcome back, Ada" {
+		t.Errorf("expected the new func on the loaded source, got %q", out)
+	}
+
+	if err := g.SetFuncs(template.FuncMap{"wave": strings.ToUpper}); err == nil {
+		t.Error("expected an error when a func the templates use goes away")
+	}
+	if err := g.SetFuncs(template.FuncMap{"partial": strings.ToUpper}); err == nil {
+		t.Error("expected an error for a reserved func name")
+	}
+	if err := g.SetFuncs(template.FuncMap{"greet": "not a func"}); err == nil {
+		t.Error("expected an error for a value that is not a func")
+	}
+	if out := render(); out != "<html><body>Welcome back, Ada</body></html>Welcome back, Ada" {
+		t.Errorf("expected failed updates to keep the previous funcs, got %q", out)
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}` +
+			`{{ range sortedKeys .Stock }}{{ . }},{{ end }}|` +
+			`{{ range sortedMap .Stock }}{{ .Key }}={{ .Value }},{{ end }}|` +
+			`{{ range sortedKeys .Sizes }}{{ . }},{{ end }}|` +
+			`{{ range sortedMap .Mixed }}{{ .Key }},{{ end }}|` +
+			`{{ join "-" (sortedKeys .Stock) }}` +
+			`{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data := map[string]any{
+		"Stock": map[string]int{"pear": 3, "apple": 1, "fig": 2, "banana": 5},
+		"Sizes": map[int]string{10: "L", 2: "S", 5: "M"},
+		"Mixed": map[any]bool{"b": true, 2: true, "a": true, 1: true},
+	}
+	want := "<html><body>apple,banana,fig,pear,|apple=1,banana=5,fig=2,pear=3,|2,5,10,|1,2,a,b,|apple-banana-fig-pear</body></html>"
+	for range 20 {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", data); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if buf.String() != want {
+			t.Fatalf("expected stable output %q, got %q", want, buf.String())
+		}
+	}
+
+	var buf strings.Builder
+	err = g.RenderPage(&buf, "app_layout", "home/index.html", map[string]any{"Stock": []int{1}})
+	if err == nil || !strings.Contains(err.Error(), "sortedKeys: expected a map, got []int") {
+		t.Errorf("expected a non-map to be rejected, got %v", err)
+	}
+}
+
+func TestJSONLD(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html": `{{ define "app_layout" }}<head><script type="application/ld+json">{{ jsonld .Meta }}</script></head>{{ end }}`,
+		"pages/blog/post.html": "---\n@context: https://schema.org\n@type: Article\n" +
+			"headline: \"Tips & tricks </script><script>alert('x')</script>\"\nwordCount: 120\n---\nPost",
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "blog/post.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := buf.String()
+	body, ok := strings.CutPrefix(out, `<head><script type="application/ld+json">`)
+	body, found := strings.CutSuffix(body, "</script></head>")
+	if !ok || !found || strings.Contains(body, "</script>") || strings.Contains(body, "&") {
+		t.Fatalf("expected escaped JSON inside a single script tag, got %q", out)
+	}
+	var data map[string]any
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		t.Fatalf("expected valid JSON, got %v in %q", err, body)
+	}
+	if data["@type"] != "Article" || data["headline"] != "Tips & tricks </script><script>alert('x')</script>" || data["wordCount"] != float64(120) {
+		t.Errorf("expected front matter values to round-trip, got %v", data)
+	}
+
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "blog/post.html", map[string]any{"Meta": func() {}}); err == nil {
+		t.Error("expected unencodable values to fail the render")
+	}
+}
@@ -1,42 +1,572 @@
 package gotemp
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
 	"os"
 	"path"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+var (
+	ErrPageNotFound      = errors.New("page template not found")
+	ErrPartialNotFound   = errors.New("partial not found")
+	ErrOutputTooLarge    = errors.New("rendered output exceeds the size limit")
+	ErrBlockNotFound     = errors.New("block not found")
+	ErrNotReady          = errors.New("templates not ready")
+	ErrNamespaceNotFound = errors.New("namespace not found")
+	ErrClosed            = errors.New("gotemp is closed")
+	ErrLayoutContract    = errors.New("page does not fit the layout contract")
+	ErrIncludeDepth      = errors.New("include depth exceeded")
+	ErrRenderPanic       = errors.New("render panicked")
+)
+
+type Renderer interface {
+	RenderPage(w io.Writer, layout, page string, data any) error
+	RenderPartial(w io.Writer, name string, data any) error
+	RenderPartialHTML(name string, data any) (template.HTML, error)
+	RenderBlock(w io.Writer, layout, page, block string, data any) error
+	RenderRoute(w io.Writer, layout, route string, data any) error
+}
+
+var _ Renderer = (*Gotemp)(nil)
+
 type Gotemp struct {
-	basePath string
-	pages    map[string]*template.Template
+	basePath         string
+	env              string
+	fsys             fs.FS
+	opts             []Option
+	optionalPages    bool
+	formatters       map[reflect.Type]func(any) string
+	trimActions      bool
+	ampTransform     bool
+	maxOutput        int64
+	maxIncludeDepth  int
+	lazyLoad         bool
+	requestHelpers   bool
+	flags            func(*http.Request) map[string]bool
+	roles            func(*http.Request) []string
+	remote           *remoteIncludes
+	includeDrafts    bool
+	lineNumbers      bool
+	transformers     map[string]func(any) (any, error)
+	buildStamp       string
+	truncationMarker string
+	themeSelector    func(*http.Request) string
+	themes           map[string]*Gotemp
+	themeName        string
+	namespaceName    string
+	buildID          string
+	pageKeyFunc      func(relPath string) string
+	escapeDebug      bool
+	sharedTemplates  bool
+	preloadLinks     bool
+	collectErrors    bool
+	skipBadTemplates bool
+	strictDefines    bool
+	layoutContract   bool
+	defaultLayout    string
+	alwaysInclude    []string
+	extraDefineGlobs []string
+	outputMiddleware []func(io.Writer) io.Writer
+	baseData         map[string]any
+	trustedFields    map[string][]string
+	missingKey       MissingKey
+	templateOptions  []string
+	jsonFields       bool
+	currentPageField bool
+	bom              bool
+	charset          string
+	errorDataContext bool
+	redactKeys       []string
+	pageData         map[string]PageLoader
+	pageDataDefaults map[string]map[string]any
+	log              *slog.Logger
+
+	renderCache     *renderCache
+	cacheTTL        time.Duration
+	pageCacheTTL    map[string]time.Duration
+	partialCache    *renderCache
+	partialCacheTTL map[string]time.Duration
+
+	notFoundPage    string
+	serverErrorPage string
+	sitemapExclude  []string
+	partialLayout   *partialLayout
+
+	set         atomic.Pointer[templateSet]
+	customFuncs atomic.Pointer[template.FuncMap]
+	assets      atomic.Bool
+	reloadMu    sync.Mutex
+
+	reloadStrategy  ReloadStrategy
+	loadedSignature atomic.Pointer[treeSignature]
+	pollInterval    time.Duration
+	stopPoll        chan struct{}
+	pollDone        chan struct{}
+	closeOnce       sync.Once
+	closed          atomic.Bool
+
+	rawCache  sync.Map
+	textCache sync.Map
+
+	edits   editFS
+	sources *cachingFS
+
+	profiling bool
+	profile   map[string]time.Duration
+	profileMu sync.Mutex
+
+	namespaces   map[string]*Gotemp
+	namespacesMu sync.RWMutex
+}
+
+type partialLayout struct {
+	full string
+	bare string
+}
+
+type templateSet struct {
+	base     *template.Template
+	layouts  *template.Template
+	scopes   []layoutScope
+	pages    map[string]*page
+	partials map[string]string
+	graph    *dependencyGraph
+	bad      []error
+}
+
+type page struct {
+	path      string
+	namespace string
+	template  *template.Template
+	scoped    map[string]*template.Template
+	files     []string
+	modTime   time.Time
+	meta      PageMeta
+	values    map[string]any
+	defaults  map[string]any
+	cache     string
+	embeds    bool
+
+	source   string
+	pristine *pristineSet
+
+	compileOnce sync.Once
+	compile     func() error
+	compileErr  error
+}
+
+func (p *page) ready() error {
+	p.compileOnce.Do(func() {
+		if p.compile != nil {
+			p.compileErr = p.compile()
+		}
+	})
+	return p.compileErr
+}
+
+func (p *page) entry(t *template.Template, name string) string {
+	if p.namespace != "" && t.Lookup(namespaced(p.namespace, name)) != nil {
+		return namespaced(p.namespace, name)
+	}
+	return name
+}
+
+func (p *page) lookup(layout string) *template.Template {
+	if scoped := p.scoped[layout]; scoped != nil {
+		return scoped
+	}
+	return p.template
 }
 
 func (tc *Gotemp) RenderPage(w io.Writer, layout, page string, data any) error {
-	pageTemplate := tc.pages[page]
-	if pageTemplate == nil {
-		return fmt.Errorf("page template not found: %s", page)
+	if err := tc.reloadIfChanged(); err != nil {
+		return err
+	}
+	set := tc.set.Load()
+	pageEntry := set.pages[page]
+	if pageEntry == nil {
+		if engine, nsPage := tc.namespacedPage(page); engine != nil {
+			return engine.RenderPage(w, layout, nsPage, data)
+		}
+		return fmt.Errorf("%w: %s", ErrPageNotFound, page)
+	}
+	if err := pageEntry.ready(); err != nil {
+		return err
+	}
+	layout = tc.pageLayout(pageEntry, layout)
+	data, err := tc.renderData(pageEntry, data)
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	t, err := tc.renderTemplate(pageEntry, layout, set.partials)
+	if err != nil {
+		return err
+	}
+	if _, stream := w.(*contextWriter); tc.renderCache != nil && !stream {
+		err = tc.renderCache.render(w, layout, page, data, tc.pageTTL(page), func(w io.Writer) error {
+			return tc.execute(w, t, pageEntry.entry(t, layout), data, true)
+		})
+	} else {
+		err = tc.execute(w, t, pageEntry.entry(t, layout), data, true)
+	}
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	return err
+}
+
+func (tc *Gotemp) RenderBlock(w io.Writer, layout, page, block string, data any) error {
+	if err := tc.reloadIfChanged(); err != nil {
+		return err
+	}
+	set := tc.set.Load()
+	pageEntry := set.pages[page]
+	if pageEntry == nil {
+		return fmt.Errorf("%w: %s", ErrPageNotFound, page)
+	}
+	if err := pageEntry.ready(); err != nil {
+		return err
+	}
+	layout = tc.pageLayout(pageEntry, layout)
+	data, err := tc.renderData(pageEntry, data)
+	if err != nil {
+		return fmt.Errorf("page %s block %s: %w", page, block, err)
+	}
+	t, err := tc.renderTemplate(pageEntry, layout, set.partials)
+	if err != nil {
+		return err
+	}
+	name := pageEntry.entry(t, block)
+	if t.Lookup(name) == nil {
+		return fmt.Errorf("%w: %s in page %s", ErrBlockNotFound, block, page)
+	}
+	err = tc.execute(w, t, name, data, false)
+	if err != nil {
+		return fmt.Errorf("page %s block %s: %w", page, block, err)
+	}
+	return err
+}
+
+func (tc *Gotemp) pageTTL(page string) time.Duration {
+	if ttl, ok := tc.pageCacheTTL[page]; ok {
+		return ttl
+	}
+	return tc.cacheTTL
+}
+
+func (tc *Gotemp) RenderPartial(w io.Writer, name string, data any) error {
+	if err := tc.reloadIfChanged(); err != nil {
+		return err
+	}
+	set := tc.set.Load()
+	if entrypoint, ok := set.partials[name]; ok {
+		name = entrypoint
+	}
+	if set.base.Lookup(name) == nil {
+		return fmt.Errorf("%w: %s", ErrPartialNotFound, name)
+	}
+	t := set.base
+	if tc.maxIncludeDepth > 0 {
+		layouts, err := clone(set.layouts)
+		if err != nil {
+			return fmt.Errorf("failed to clone layout template: %w", err)
+		}
+		t = tc.bindRender(tc.bind(layouts, set.partials), set.partials, newRenderState())
+	}
+	err := tc.execute(w, t, name, data, false)
+	if err != nil {
+		return fmt.Errorf("partial %s: %w", name, err)
+	}
+	return err
+}
+
+func (tc *Gotemp) RenderPartialHTML(name string, data any) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := tc.RenderPartial(&buf, name, data); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
+
+func (tc *Gotemp) RenderPageBytes(layout, page string, data any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tc.RenderPage(&buf, layout, page, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (tc *Gotemp) RenderPageMulti(writers []io.Writer, layout, page string, data any) error {
+	output, err := tc.RenderPageBytes(layout, page, data)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i, w := range writers {
+		if _, err := w.Write(output); err != nil {
+			errs = append(errs, fmt.Errorf("writer %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (tc *Gotemp) execute(w io.Writer, t *template.Template, name string, data any, page bool) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = tc.recovered(r)
+		}
+	}()
+	recorder, _ := w.(assetRecorder)
+	stream, _ := w.(*contextWriter)
+	var closers []io.Closer
+	if page {
+		w, closers = tc.pageWriter(w)
+	}
+	if tc.maxOutput > 0 {
+		w = &limitWriter{w: w, remaining: tc.maxOutput, limit: tc.maxOutput}
+	}
+	if tc.trimActions && stream == nil {
+		tw := &trimWriter{w: w}
+		w, closers = tw, append(closers, tw)
+	}
+	if tc.assets.Load() {
+		aw := &assetWriter{w: w, recorder: recorder, stream: stream != nil}
+		w, closers = aw, append(closers, aw)
+		if tc.maxOutput > 0 {
+			w = &limitWriter{w: w, remaining: tc.maxOutput, limit: tc.maxOutput}
+		}
+	}
+	if stream != nil {
+		w = &cancelWriter{w: w, stream: stream}
+	}
+	if err := t.ExecuteTemplate(w, name, data); err != nil {
+		return tc.withDataContext(err, data)
+	}
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tc *Gotemp) pageWriter(w io.Writer) (io.Writer, []io.Closer) {
+	var closers []io.Closer
+	for i := len(tc.outputMiddleware) - 1; i >= 0; i-- {
+		wrapped := tc.outputMiddleware[i](w)
+		if closer, ok := wrapped.(io.Closer); ok && wrapped != w {
+			closers = append(closers, closer)
+		}
+		w = wrapped
+	}
+	if tc.ampTransform {
+		aw := &ampWriter{w: w}
+		w, closers = aw, append(closers, aw)
 	}
-	return pageTemplate.ExecuteTemplate(w, layout, data)
+	if tc.buildStamp != "" {
+		sw := &stampWriter{w: w, stamp: stampComment(tc.buildStamp)}
+		w, closers = sw, append(closers, sw)
+	}
+	return w, closers
+}
+
+func (tc *Gotemp) PageTemplates(page string) ([]string, error) {
+	pageEntry := tc.set.Load().pages[page]
+	if pageEntry == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPageNotFound, page)
+	}
+	if err := pageEntry.ready(); err != nil {
+		return nil, err
+	}
+	return pageEntry.templateNames(), nil
+}
+
+func (p *page) templateNames() []string {
+	var names []string
+	for _, t := range p.template.Templates() {
+		if name, ok := p.visibleName(t.Name()); ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (tc *Gotemp) SourceFiles(page string) []string {
+	pageEntry := tc.set.Load().pages[page]
+	if pageEntry == nil {
+		return nil
+	}
+	files := make([]string, len(pageEntry.files))
+	for i, file := range pageEntry.files {
+		files[i] = path.Join(tc.basePath, file)
+	}
+	return files
+}
+
+func New(basePath string, opts ...Option) (*Gotemp, error) {
+	return newGotemp(basePath, os.DirFS(basePath), opts)
+}
+
+func NewFS(fsys fs.FS, opts ...Option) (*Gotemp, error) {
+	return newGotemp("", fsys, opts)
 }
 
-func New(basePath string) (*Gotemp, error) {
-	gotemp := Gotemp{basePath: basePath}
+func NewFromSources(sources map[string]string, opts ...Option) (*Gotemp, error) {
+	return newGotemp("", sourceFS(sources), opts)
+}
+
+func newGotemp(basePath string, fsys fs.FS, opts []Option) (*Gotemp, error) {
+	gotemp := &Gotemp{basePath: basePath, opts: opts}
+	for _, opt := range opts {
+		opt(gotemp)
+	}
+	if custom := gotemp.customFuncs.Load(); custom != nil {
+		if err := gotemp.checkFuncs(*custom); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkTemplateOptions(gotemp.templateOptions); err != nil {
+		return nil, err
+	}
+	if gotemp.env != "" {
+		if !fs.ValidPath(gotemp.env) || gotemp.env == "." {
+			return nil, fmt.Errorf("invalid environment name %q", gotemp.env)
+		}
+		env, err := fs.Sub(fsys, path.Join("env", gotemp.env))
+		if err != nil {
+			return nil, fmt.Errorf("invalid environment name %q: %w", gotemp.env, err)
+		}
+		fsys = overlayFS{upper: env, lower: fsys}
+	}
+	gotemp.fsys = overlayFS{upper: &gotemp.edits, lower: fsys}
+	gotemp.sources = &cachingFS{fsys: gotemp.fsys}
 	err := gotemp.loadPages()
 	if err != nil {
 		return nil, err
 	}
-	return &gotemp, nil
+	if gotemp.themeSelector != nil {
+		if err := gotemp.loadThemes(); err != nil {
+			return nil, err
+		}
+	}
+	if gotemp.pollInterval > 0 {
+		gotemp.startPolling()
+	}
+	return gotemp, nil
+}
+
+func (tc *Gotemp) Reload() error {
+	tc.reloadMu.Lock()
+	defer tc.reloadMu.Unlock()
+	if err := tc.loadPages(); err != nil {
+		return err
+	}
+	return tc.forEngines((*Gotemp).Reload)
+}
+
+func (tc *Gotemp) OverlayFS(fsys fs.FS) (*Gotemp, error) {
+	opts := append(slices.Clip(tc.opts), WithEnv(""), WithPollReload(0))
+	return newGotemp("", overlayFS{upper: fsys, lower: tc.sources}, opts)
 }
 
 func (tc *Gotemp) loadPages() error {
+	return tc.loadPagesKeeping(nil)
+}
+
+func (tc *Gotemp) loadPagesKeeping(keep map[string]*page) error {
+	if tc.closed.Load() {
+		return ErrClosed
+	}
+	tc.sources.reset()
+	err := tc.loadSet(keep)
+	if err != nil && tc.collectErrors {
+		if errs := tc.checkFiles(); len(errs) > 1 {
+			return errors.Join(errs...)
+		}
+	}
+	return err
+}
+
+func (tc *Gotemp) checkPage(name string) error {
+	content, err := tc.readTemplate(name)
+	if err == nil {
+		_, err = parseTrees(name, content)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse page template %s: %w", name, err)
+	}
+	return nil
+}
+
+func (tc *Gotemp) BadTemplates() []error {
+	if set := tc.set.Load(); set != nil {
+		return slices.Clone(set.bad)
+	}
+	return nil
+}
+
+func (tc *Gotemp) checkFiles() []error {
+	var errs []error
+	for _, root := range []string{"root.html", "partials", "layouts", "pages"} {
+		err := fs.WalkDir(tc.fsys, root, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() || !isTemplatePath(name) {
+				return err
+			}
+			content, err := tc.readTemplate(name)
+			if err == nil {
+				_, err = template.New(path.Base(name)).Funcs(tc.funcs()).Parse(content)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (tc *Gotemp) loadSet(keep map[string]*page) error {
+	var sig treeSignature
+	if tc.reloadStrategy == Checksum || tc.pollInterval > 0 {
+		var err error
+		if sig, err = tc.signature(); err != nil {
+			return fmt.Errorf("failed to compute template signature: %w", err)
+		}
+	}
+
+	var scope string
+	if tc.renderCache != nil && tc.renderCache.backend != nil {
+		sources, err := tc.sourceHash()
+		if err != nil {
+			return fmt.Errorf("failed to hash template sources: %w", err)
+		}
+		scope = strings.Join([]string{tc.buildID, tc.themeName, tc.namespaceName, sources}, "\x00")
+	}
+
 	root, err := tc.loadRoot()
 	if err != nil {
 		return fmt.Errorf("failed to load root template: %w", err)
 	}
 
-	partials, err := tc.loadPartials(root)
+	partials, partialNames, err := tc.loadPartials(root)
 	if err != nil {
 		return fmt.Errorf("failed to load partials: %w", err)
 	}
@@ -46,18 +576,92 @@ func (tc *Gotemp) loadPages() error {
 		return fmt.Errorf("failed to load layouts: %w", err)
 	}
 
-	pages := make(map[string]*template.Template)
-	pagesPath := path.Join(tc.basePath, "pages")
+	scopes, err := tc.loadLayoutScopes(layouts)
+	if err != nil {
+		return fmt.Errorf("failed to load layout scoped partials: %w", err)
+	}
 
-	entries, err := os.ReadDir(pagesPath)
+	partialFiles, err := tc.partialFiles()
+	if err != nil {
+		return fmt.Errorf("failed to list partial files: %w", err)
+	}
+	sharedFiles, err := tc.sharedFiles()
 	if err != nil {
+		return fmt.Errorf("failed to list shared page files: %w", err)
+	}
+	layoutFiles, err := tc.layoutFiles()
+	if err != nil {
+		return fmt.Errorf("failed to list layout files: %w", err)
+	}
+	scopeFiles, err := tc.globFiles("layouts/*/*.html")
+	if err != nil {
+		return fmt.Errorf("failed to list layout files: %w", err)
+	}
+	for _, file := range scopeFiles {
+		if !slices.Contains(layoutFiles, file) {
+			layoutFiles = append(layoutFiles, file)
+		}
+	}
+	alwaysFiles, err := tc.alwaysFiles()
+	if err != nil {
+		return fmt.Errorf("failed to list always included files: %w", err)
+	}
+	baseFiles := append([]string{"root.html"}, alwaysFiles...)
+	for _, file := range partialFiles {
+		if !slices.Contains(alwaysFiles, file) {
+			baseFiles = append(baseFiles, file)
+		}
+	}
+	baseFiles = append(baseFiles, sharedFiles...)
+	extraFiles, err := tc.extraDefineFiles()
+	if err != nil {
+		return fmt.Errorf("failed to list extra define files: %w", err)
+	}
+	for _, file := range extraFiles {
+		if !slices.Contains(baseFiles, file) {
+			baseFiles = append(baseFiles, file)
+		}
+	}
+	baseFiles = append(baseFiles, layoutFiles...)
+
+	var defines map[string]definition
+	if tc.strictDefines {
+		if defines, err = tc.baseDefines(partialFiles, sharedFiles); err != nil {
+			return err
+		}
+	}
+
+	pages := make(map[string]*page)
+	pagesPath := "pages"
+	var bad []error
+	skip := func(pageKey, relPath string, err error) error {
+		if !tc.skipBadTemplates {
+			return err
+		}
+		bad = append(bad, err)
+		tc.logger().Error("gotemp: skipping bad page template", "page", relPath, "error", err)
+		if previous := tc.set.Load(); previous != nil {
+			if old := previous.pages[pageKey]; old != nil && old.path == relPath && old.ready() == nil {
+				pages[pageKey] = old
+			}
+		}
+		return nil
+	}
+
+	entries, err := fs.ReadDir(tc.fsys, pagesPath)
+	if errors.Is(err, fs.ErrNotExist) && tc.optionalPages {
+		entries = nil
+	} else if err != nil {
 		return fmt.Errorf("could not read the pages directory: %w", err)
 	}
 
 	for _, entry := range entries {
 		dirName := entry.Name()
+		if isSharedDir(dirName) {
+			continue
+		}
 		dirPath := path.Join(pagesPath, dirName)
-		files, err := os.ReadDir(dirPath)
+		files, err := fs.ReadDir(tc.fsys, dirPath)
 		if err != nil {
 			return fmt.Errorf("could not read the subpages directory %s: %w", dirPath, err)
 		}
@@ -66,41 +670,355 @@ func (tc *Gotemp) loadPages() error {
 			if !file.IsDir() {
 				fileName := file.Name()
 				name := path.Join(pagesPath, dirName, fileName)
-				layout, err := clone(layouts)
-				if err != nil {
-					return fmt.Errorf("failed to clone layout template: %w", err)
+				if slices.Contains(extraFiles, name) {
+					continue
+				}
+				relPath := path.Join(dirName, fileName)
+				pageKey := tc.pageKey(relPath)
+				if existing, ok := pages[pageKey]; ok {
+					return fmt.Errorf("pages %s and %s both map to key %q", existing.path, relPath, pageKey)
+				}
+				if defines != nil {
+					if err := tc.claimDefines(defines, name, false); err != nil {
+						return err
+					}
+				}
+				if kept := keep[pageKey]; kept != nil && kept.path == relPath {
+					pages[pageKey] = kept
+					continue
+				}
+				pageEntry := &page{
+					path:  relPath,
+					files: append(append([]string(nil), baseFiles...), name),
 				}
-				pageKey := path.Join(dirName, fileName)
-				pages[pageKey], err = layout.ParseFiles(name)
+				pageEntry.modTime, err = tc.newestModTime(pageEntry.files)
 				if err != nil {
-					return fmt.Errorf("failed to parse page template %s: %w", name, err)
+					return fmt.Errorf("failed to stat page template %s: %w", name, err)
 				}
+				if err := tc.pageMeta(pageEntry, name, pageKey); err != nil {
+					if err := skip(pageKey, relPath, fmt.Errorf("failed to read front matter of %s: %w", name, err)); err != nil {
+						return err
+					}
+					continue
+				}
+				if pageEntry.values["draft"] == true && !tc.includeDrafts {
+					continue
+				}
+				pageEntry.defaults = tc.pageDefaults(pageEntry)
+				if tc.sharedTemplates {
+					if tc.skipBadTemplates {
+						if err := tc.checkPage(name); err != nil {
+							skip(pageKey, relPath, err)
+							continue
+						}
+					}
+					pageEntry.namespace = pageKey
+					pages[pageKey] = pageEntry
+					continue
+				}
+				pageEntry.compile = func() error {
+					return tc.compilePage(pageEntry, name, layouts, scopes, partialNames)
+				}
+				if !tc.lazyLoad {
+					if err := pageEntry.ready(); err != nil {
+						if err := skip(pageKey, relPath, err); err != nil {
+							return err
+						}
+						continue
+					}
+				}
+				pages[pageKey] = pageEntry
 			}
 		}
 	}
 
-	tc.pages = pages
+	if tc.layoutContract {
+		if err := tc.checkLayoutContracts(pages); err != nil {
+			return err
+		}
+	}
+
+	var base *template.Template
+	if tc.sharedTemplates {
+		base, err = tc.compileShared(pages, layouts, scopes, partialNames)
+		if err != nil {
+			return err
+		}
+	} else if base, err = clone(layouts); err != nil {
+		return fmt.Errorf("failed to clone layout template: %w", err)
+	}
+
+	graph, err := tc.buildGraph(layouts, scopes, pages, partialNames)
+	if err != nil {
+		return fmt.Errorf("failed to build the dependency graph: %w", err)
+	}
+	if err := graph.embedCycle(); err != nil {
+		return err
+	}
+
+	tc.set.Store(&templateSet{
+		base:     tc.bind(base, partialNames),
+		graph:    graph,
+		layouts:  layouts,
+		scopes:   scopes,
+		pages:    pages,
+		partials: partialNames,
+		bad:      bad,
+	})
+	if tc.reloadStrategy == Checksum || tc.pollInterval > 0 {
+		tc.loadedSignature.Store(&sig)
+	}
+	if tc.renderCache != nil && tc.renderCache.backend != nil {
+		tc.renderCache.setScope(scope)
+	}
+	tc.clearCaches()
+	return nil
+}
+
+func (tc *Gotemp) clearCaches() {
+	tc.rawCache.Clear()
+	tc.textCache.Clear()
+	if tc.renderCache != nil {
+		tc.renderCache.clear()
+	}
+	if tc.partialCache != nil {
+		tc.partialCache.clear()
+	}
+}
+
+func (tc *Gotemp) pageKey(relPath string) string {
+	if tc.pageKeyFunc != nil {
+		return tc.pageKeyFunc(relPath)
+	}
+	return relPath
+}
+
+func (tc *Gotemp) compilePage(pageEntry *page, name string, layouts *template.Template, scopes []layoutScope, partialNames map[string]string) error {
+	content, err := tc.readTemplate(name)
+	if err != nil {
+		return fmt.Errorf("failed to parse page template %s: %w", name, err)
+	}
+	return tc.compileSource(pageEntry, name, content, layouts, scopes, partialNames)
+}
+
+func (tc *Gotemp) compileSource(pageEntry *page, name, content string, layouts *template.Template, scopes []layoutScope, partialNames map[string]string) error {
+	pageEntry.source = content
+	caller := &renderState{embeds: []string{pageEntry.meta.Page}}
+	build := func() (*template.Template, map[string]*template.Template, error) {
+		return tc.parsePage(name, content, caller, layouts, scopes, partialNames)
+	}
+	var err error
+	pageEntry.template, pageEntry.scoped, err = build()
+	if err != nil {
+		return err
+	}
+	pageEntry.embeds = usesIdentifier(pageEntry.template, embedFunc)
+	for _, scoped := range pageEntry.scoped {
+		pageEntry.embeds = pageEntry.embeds || usesIdentifier(scoped, embedFunc)
+	}
+	pageEntry.pristine = &pristineSet{build: build}
 	return nil
 }
 
+func (tc *Gotemp) parsePage(name, content string, caller *renderState, layouts *template.Template, scopes []layoutScope, partialNames map[string]string) (*template.Template, map[string]*template.Template, error) {
+	parse := func(set *template.Template) (*template.Template, error) {
+		t, err := clone(set)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clone layout template: %w", err)
+		}
+		page := t
+		if path.Base(name) != t.Name() {
+			page = t.New(path.Base(name))
+		}
+		if _, err := page.Parse(content); err != nil {
+			return nil, fmt.Errorf("failed to parse page template %s: %w", name, err)
+		}
+		return tc.bind(t, partialNames).Funcs(template.FuncMap{embedFunc: tc.renderPageFunc(caller)}), nil
+	}
+	t, err := parse(layouts)
+	if err != nil {
+		return nil, nil, err
+	}
+	scoped := make(map[string]*template.Template)
+	for _, scope := range scopes {
+		scopedPage, err := parse(scope.template)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, layoutName := range scope.layouts {
+			scoped[layoutName] = scopedPage
+		}
+	}
+	return t, scoped, nil
+}
+
 func (tc *Gotemp) loadRoot() (*template.Template, error) {
-	template, err := template.ParseGlob(path.Join(tc.basePath, "root.html"))
+	template, err := tc.parseFiles(template.New("root.html").Option(tc.parseOptions()...).Funcs(tc.funcs()), "root.html")
 	if err != nil {
 		return nil, err
 	}
 	return template, nil
 }
 
-func (tc *Gotemp) loadPartials(root *template.Template) (*template.Template, error) {
+func (tc *Gotemp) loadPartials(root *template.Template) (*template.Template, map[string]string, error) {
 	clonedRoot, err := clone(root)
 	if err != nil {
-		return nil, fmt.Errorf("failed to clone root template: %w", err)
+		return nil, nil, fmt.Errorf("failed to clone root template: %w", err)
+	}
+	alwaysFiles, err := tc.alwaysFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, file := range alwaysFiles {
+		content, err := tc.readTemplate(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := clonedRoot.New(templateName(file)).Parse(content); err != nil {
+			return nil, nil, err
+		}
+	}
+	files, err := tc.partialFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	partials := make(map[string]string)
+	for _, file := range files {
+		content, err := fs.ReadFile(tc.fsys, file)
+		if err != nil {
+			return nil, nil, err
+		}
+		name := strings.TrimPrefix(file, "partials/")
+		treeSet, err := parseTrees(name, string(content))
+		if err != nil {
+			return nil, nil, err
+		}
+		if !slices.Contains(alwaysFiles, file) {
+			if _, err := clonedRoot.New(name).Parse(string(content)); err != nil {
+				return nil, nil, err
+			}
+		}
+		partials[name] = partialEntrypoint(name, treeSet)
+	}
+
+	sharedFiles, err := tc.sharedFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, file := range sharedFiles {
+		content, err := fs.ReadFile(tc.fsys, file)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := clonedRoot.New(strings.TrimPrefix(file, "pages/")).Parse(string(content)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	extraFiles, err := tc.extraDefineFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, file := range extraFiles {
+		if slices.Contains(alwaysFiles, file) || slices.Contains(files, file) || slices.Contains(sharedFiles, file) {
+			continue
+		}
+		content, err := tc.defineSource(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read extra define file %s: %w", file, err)
+		}
+		if _, err := clonedRoot.New(file).Parse(content); err != nil {
+			return nil, nil, err
+		}
 	}
-	template, err := clonedRoot.ParseGlob(path.Join(tc.basePath, "partials", "*.html"))
+	return clonedRoot, partials, nil
+}
+
+func (tc *Gotemp) defineSource(file string) (string, error) {
+	content, err := fs.ReadFile(tc.fsys, file)
 	if err != nil {
+		return "", err
+	}
+	_, body, err := splitFrontMatter(string(content))
+	return body, err
+}
+
+func (tc *Gotemp) sharedFiles() ([]string, error) {
+	entries, err := fs.ReadDir(tc.fsys, "pages")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
 		return nil, err
 	}
-	return template, nil
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !isSharedDir(entry.Name()) {
+			continue
+		}
+		err := fs.WalkDir(tc.fsys, path.Join("pages", entry.Name()), func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && path.Ext(name) == ".html" {
+				files = append(files, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func isSharedDir(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+func (tc *Gotemp) alwaysFiles() ([]string, error) {
+	return tc.matchGlobs("always include", tc.alwaysInclude)
+}
+
+func (tc *Gotemp) extraDefineFiles() ([]string, error) {
+	return tc.matchGlobs("extra define", tc.extraDefineGlobs)
+}
+
+func (tc *Gotemp) matchGlobs(kind string, patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(tc.fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s pattern matches no files: %#q", kind, pattern)
+		}
+		for _, match := range matches {
+			if !slices.Contains(files, match) {
+				files = append(files, match)
+			}
+		}
+	}
+	return files, nil
+}
+
+func (tc *Gotemp) partialFiles() ([]string, error) {
+	var files []string
+	err := fs.WalkDir(tc.fsys, "partials", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && path.Ext(name) == ".html" {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return files, err
 }
 
 func (tc *Gotemp) loadLayouts(partials *template.Template) (*template.Template, error) {
@@ -108,13 +1026,172 @@ func (tc *Gotemp) loadLayouts(partials *template.Template) (*template.Template,
 	if err != nil {
 		return nil, fmt.Errorf("failed to clone partials template: %w", err)
 	}
-	template, err := clonedPartials.ParseGlob(path.Join(tc.basePath, "layouts", "*.html"))
+	files, err := tc.layoutFiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("pattern matches no files: %#q", "layouts/*.html")
+	}
+	template, err := tc.parseFiles(clonedPartials, files...)
 	if err != nil {
 		return nil, err
 	}
 	return template, nil
 }
 
+func (tc *Gotemp) layoutFiles() ([]string, error) {
+	files, err := tc.layoutPartFiles()
+	if err != nil {
+		return nil, err
+	}
+	layoutFiles, err := tc.globFiles("layouts/*.html")
+	if err != nil {
+		return nil, err
+	}
+	return append(files, layoutFiles...), nil
+}
+
+func (tc *Gotemp) layoutPartFiles() ([]string, error) {
+	entries, err := fs.ReadDir(tc.fsys, "layouts")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		dir := path.Join("layouts", entry.Name())
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := fs.Stat(tc.fsys, dir+".html"); err == nil {
+			continue
+		}
+		err := fs.WalkDir(tc.fsys, dir, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && path.Ext(name) == ".html" {
+				files = append(files, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+type layoutScope struct {
+	layouts  []string
+	template *template.Template
+}
+
+func (tc *Gotemp) loadLayoutScopes(layouts *template.Template) ([]layoutScope, error) {
+	layoutsPath := "layouts"
+	entries, err := fs.ReadDir(tc.fsys, layoutsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var scopes []layoutScope
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dirName := entry.Name()
+		layoutFile := path.Join(layoutsPath, dirName+".html")
+		if _, err := fs.Stat(tc.fsys, layoutFile); err != nil {
+			continue
+		}
+		dir := path.Join(layoutsPath, dirName)
+		dirEntries, err := fs.ReadDir(tc.fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, dirEntry := range dirEntries {
+			if dirEntry.IsDir() {
+				return nil, fmt.Errorf("%s holds layout-scoped partials because %s exists, so it cannot have subfolders like %s; keep split layout files in a folder without a layout file of the same name", dir, layoutFile, dirEntry.Name())
+			}
+		}
+		overrides, err := tc.globFiles(path.Join(dir, "*.html"))
+		if err != nil {
+			return nil, err
+		}
+		if len(overrides) == 0 {
+			continue
+		}
+
+		defined, err := tc.parseFiles(template.New(path.Base(layoutFile)), layoutFile)
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, t := range defined.Templates() {
+			if t.Name() != path.Base(layoutFile) {
+				names = append(names, t.Name())
+			}
+		}
+
+		clonedLayouts, err := clone(layouts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clone layouts template: %w", err)
+		}
+		scopedLayouts, err := tc.parseFiles(clonedLayouts, overrides...)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, layoutScope{layouts: names, template: scopedLayouts})
+	}
+	return scopes, nil
+}
+
+func (tc *Gotemp) parseFiles(t *template.Template, files ...string) (*template.Template, error) {
+	for _, file := range files {
+		content, err := tc.readTemplate(file)
+		if err != nil {
+			return nil, err
+		}
+		name := path.Base(file)
+		tmpl := t
+		if name != t.Name() {
+			tmpl = t.New(name)
+		}
+		if _, err := tmpl.Parse(content); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (tc *Gotemp) globFiles(patterns ...string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(tc.fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func (tc *Gotemp) newestModTime(files []string) (time.Time, error) {
+	var newest time.Time
+	for _, file := range files {
+		info, err := fs.Stat(tc.fsys, file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return newest, nil
+}
+
 func clone(temp *template.Template) (*template.Template, error) {
 	cloned, err := temp.Clone()
 	if err != nil {
@@ -2,9 +2,16 @@ package gotemp_test
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 
 	"github.com/bllyanos/gotemp"
 )
@@ -140,3 +147,787 @@ func TestRenderToStdout(t *testing.T) {
 		t.Error("expected 'Homepage' in stdout output")
 	}
 }
+
+func TestLayoutScopedPartials(t *testing.T) {
+	g, err := gotemp.New("testdata/scoped")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var app, marketing bytes.Buffer
+	if err := g.RenderPage(&app, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.RenderPage(&marketing, "marketing_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !strings.Contains(app.String(), "Global footer") {
+		t.Error("expected global footer in app layout output")
+	}
+	if strings.Contains(app.String(), "Marketing footer") {
+		t.Error("expected marketing footer not to leak into app layout output")
+	}
+	if !strings.Contains(marketing.String(), "Marketing footer") {
+		t.Error("expected marketing footer in marketing layout output")
+	}
+	if !strings.Contains(marketing.String(), "Welcome") {
+		t.Error("expected page content in marketing layout output")
+	}
+}
+
+func TestLayoutSubfolders(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layouts/app.html":               `{{ define "app_layout" }}<main>{{ block "content" . }}{{ end }}</main>{{ end }}`,
+		"layouts/site/shell.html":        `{{ define "site_layout" }}{{ template "site_header" . }}<main>{{ block "content" . }}{{ end }}</main>{{ template "site_footer" . }}{{ end }}`,
+		"layouts/site/header.html":       `{{ define "site_header" }}<header>{{ template "site_nav" . }}</header>{{ end }}`,
+		"layouts/site/parts/nav.html":    `{{ define "site_nav" }}<nav>Nav</nav>{{ end }}`,
+		"layouts/site/parts/footer.html": `{{ define "site_footer" }}<footer>Footer</footer>{{ end }}`,
+		"pages/home/index.html":          `{{ define "content" }}Home{{ end }}`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithStrictDefines(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for layout, want := range map[string]string{
+		"site_layout": "<header><nav>Nav</nav></header><main>Home</main><footer>Footer</footer>",
+		"app_layout":  "<main>Home</main>",
+	} {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, layout, "home/index.html", nil); err != nil {
+			t.Fatalf("expected %s to render, got %v", layout, err)
+		}
+		if buf.String() != want {
+			t.Errorf("expected %s to render %q, got %q", layout, want, buf.String())
+		}
+	}
+	if got := g.DependentsOf("site_footer"); !slices.Equal(got, []string{"home/index.html"}) {
+		t.Errorf("expected pages to depend on subfolder layout defines, got %v", got)
+	}
+
+	dir = writeTemplates(t, map[string]string{
+		"layouts/site/shell.html": `{{ define "site_layout" }}<main>{{ block "content" . }}{{ end }}{{ block "aside" . }}{{ end }}</main>{{ end }}`,
+		"pages/home/index.html":   "---\nlayout: site_layout\n---\n{{ define \"content\" }}Home{{ end }}",
+	})
+	if _, err := gotemp.New(dir, gotemp.WithEnforceLayoutContract(true)); !errors.Is(err, gotemp.ErrLayoutContract) || !strings.Contains(err.Error(), "aside") {
+		t.Errorf("expected the contract of a subfolder layout to be enforced, got %v", err)
+	}
+
+	dir = writeTemplates(t, map[string]string{
+		"layouts/app/_footer.html":     `{{ define "footer" }}App footer{{ end }}`,
+		"layouts/app/parts/shell.html": `{{ define "app_shell" }}{{ end }}`,
+	})
+	if _, err := gotemp.New(dir); err == nil || !strings.Contains(err.Error(), "layouts/app holds layout-scoped partials") {
+		t.Errorf("expected subfolders in a layout-scoped partials folder to fail, got %v", err)
+	}
+}
+
+func TestAlwaysInclude(t *testing.T) {
+	files := map[string]string{
+		"macros/icons.html":     `{{ define "icon" }}<i>{{ . }}</i>{{ end }}{{ define "badge" }}<b>{{ . }}</b>{{ end }}`,
+		"partials/badge.html":   `{{ define "badge" }}<em>{{ . }}</em>{{ end }}`,
+		"layouts/app.html":      `{{ define "app_layout" }}{{ template "icon" "menu" }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ template "icon" "home" }}{{ template "badge" "new" }}{{ end }}`,
+	}
+	g, err := gotemp.New(writeTemplates(t, files), gotemp.WithAlwaysInclude("macros/*.html"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "<i>menu</i><i>home</i><em>new</em>"; buf.String() != want {
+		t.Errorf("expected the macros in the layout and page, with partials loaded after them, got %q, want %q", buf.String(), want)
+	}
+
+	g, err = gotemp.New(writeTemplates(t, files))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.RenderPage(io.Discard, "app_layout", "home/index.html", nil); err == nil {
+		t.Error("expected the macros to be unavailable without WithAlwaysInclude")
+	}
+	if _, err := gotemp.New(writeTemplates(t, files), gotemp.WithAlwaysInclude("missing/*.html")); err == nil || !strings.Contains(err.Error(), "missing/*.html") {
+		t.Errorf("expected a pattern without matches to fail, got %v", err)
+	}
+}
+
+func TestExtraDefineGlobs(t *testing.T) {
+	files := map[string]string{
+		"layouts/app.html":        `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/promo/macros.html": `{{ define "cta" }}<a>{{ . }}</a>{{ end }}`,
+		"pages/home/index.html":   `{{ define "content" }}{{ template "cta" "buy" }}{{ end }}`,
+	}
+	g, err := gotemp.New(writeTemplates(t, files), gotemp.WithExtraDefineGlobs("pages/promo/macros.html"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "<a>buy</a>"; buf.String() != want {
+		t.Errorf("expected the extra define in the page, got %q, want %q", buf.String(), want)
+	}
+	if slices.Contains(g.ListPages(), "promo/macros.html") {
+		t.Errorf("expected the extra define file not to be a page, got %v", g.ListPages())
+	}
+	if _, err := gotemp.New(writeTemplates(t, files), gotemp.WithExtraDefineGlobs("missing/*.html")); err == nil || !strings.Contains(err.Error(), "missing/*.html") {
+		t.Errorf("expected a pattern without matches to fail, got %v", err)
+	}
+	files["pages/promo/macros.html"] = "---\ntitle: Promo\n" + files["pages/promo/macros.html"]
+	_, err = gotemp.New(writeTemplates(t, files), gotemp.WithExtraDefineGlobs("pages/promo/macros.html"), gotemp.WithStrictDefines(true))
+	if err == nil || !strings.Contains(err.Error(), "pages/promo/macros.html") {
+		t.Errorf("expected an unreadable extra define file to fail the load, got %v", err)
+	}
+}
+
+func TestSourceFiles(t *testing.T) {
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	files := g.SourceFiles("home/index.html")
+	expected := []string{
+		"examples/root.html",
+		"examples/partials/_header.html",
+		"examples/layouts/app.html",
+		"examples/layouts/auth.html",
+		"examples/pages/home/index.html",
+	}
+	for _, want := range expected {
+		found := false
+		for _, file := range files {
+			if file == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %s in source files %v", want, files)
+		}
+	}
+	for _, file := range files {
+		if file == "examples/pages/auth/sign_in.html" {
+			t.Error("expected other page files not to be listed")
+		}
+	}
+
+	if g.SourceFiles("nonexistent/page.html") != nil {
+		t.Error("expected nil source files for non-existent page")
+	}
+}
+
+func TestPartialByPath(t *testing.T) {
+	g, err := gotemp.New("testdata/nested")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = g.RenderPage(&buf, "app_layout", "home/index.html", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	result := buf.String()
+	if !strings.Contains(result, "<header>Nested App</header>") {
+		t.Error("expected top-level partial resolved by path in output")
+	}
+	if !strings.Contains(result, `<input type="text" name="email">`) {
+		t.Error("expected nested define-only partial resolved by path in output")
+	}
+	if !strings.Contains(result, `<div class="card">Hello &lt;World&gt;</div>`) {
+		t.Error("expected nested body partial resolved by path with escaped data in output")
+	}
+}
+
+func TestPartialByPathNotFound(t *testing.T) {
+	g, err := gotemp.New("testdata/nested")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = g.RenderPage(&buf, "app_layout", "home/missing.html", nil)
+	if err == nil || !strings.Contains(err.Error(), "partial not found: forms/missing.html") {
+		t.Errorf("expected partial not found error, got %v", err)
+	}
+}
+
+func TestOptionalPages(t *testing.T) {
+	_, err := gotemp.New("testdata/components")
+	if err == nil {
+		t.Fatal("expected error for missing pages directory without WithOptionalPages")
+	}
+
+	g, err := gotemp.New("testdata/components", gotemp.WithOptionalPages(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = g.RenderPage(&buf, "app_layout", "home/index.html", nil)
+	if !errors.Is(err, gotemp.ErrPageNotFound) {
+		t.Errorf("expected ErrPageNotFound, got %v", err)
+	}
+
+	buf.Reset()
+	if err := g.RenderPartial(&buf, "button.html", "Save"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `<button class="btn">Save</button>`) {
+		t.Errorf("expected rendered button partial, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.RenderPartial(&buf, "badge", "new"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != `<span class="badge">new</span>` {
+		t.Errorf("expected rendered badge define, got %q", buf.String())
+	}
+
+	err = g.RenderPartial(&buf, "missing.html", nil)
+	if !errors.Is(err, gotemp.ErrPartialNotFound) {
+		t.Errorf("expected ErrPartialNotFound, got %v", err)
+	}
+}
+
+func writeTemplates(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	defaults := map[string]string{
+		"root.html":        `{{ define "__start" }}<html><body>{{ end }}{{ define "__end" }}</body></html>{{ end }}`,
+		"layouts/app.html": `{{ define "app_layout" }}{{ template "__start" . }}{{ block "content" . }}{{ end }}{{ template "__end" . }}{{ end }}`,
+	}
+	for name, content := range defaults {
+		if _, ok := files[name]; !ok {
+			files[name] = content
+		}
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestNewFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root.html":             {Data: []byte(`{{ define "__start" }}<html>{{ end }}{{ define "__end" }}</html>{{ end }}`)},
+		"layouts/app.html":      {Data: []byte(`{{ define "app_layout" }}{{ template "__start" . }}{{ block "content" . }}{{ end }}{{ template "__end" . }}{{ end }}`)},
+		"pages/home/index.html": {Data: []byte(`{{ define "content" }}From memory{{ end }}`)},
+	}
+	g, err := gotemp.NewFS(fsys)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<html>From memory</html>" {
+		t.Errorf("expected page rendered from fs.FS, got %q", buf.String())
+	}
+	if files := g.SourceFiles("home/index.html"); files[len(files)-1] != "pages/home/index.html" {
+		t.Errorf("expected fs-relative source files, got %v", files)
+	}
+}
+
+func TestPageTemplates(t *testing.T) {
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	names, err := g.PageTemplates("home/index.html")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, want := range []string{"__start", "__end", "_header", "app_layout", "auth_layout", "content", "index.html"} {
+		found := false
+		for _, name := range names {
+			if name == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %s in page templates %v", want, names)
+		}
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i-1] > names[i] {
+			t.Errorf("expected sorted template names, got %v", names)
+			break
+		}
+	}
+
+	_, err = g.PageTemplates("nonexistent/page.html")
+	if !errors.Is(err, gotemp.ErrPageNotFound) {
+		t.Errorf("expected ErrPageNotFound, got %v", err)
+	}
+}
+
+func TestSharedPageDefines(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/_shared/cards.html": `{{ define "product_card" }}<div class="card">{{ . }}</div>{{ end }}`,
+		"pages/shop/index.html":    `{{ define "content" }}{{ template "product_card" "Shoes" }}{{ end }}`,
+		"pages/shop/sale.html":     `{{ define "content" }}{{ template "product_card" "Hats" }}{{ end }}`,
+		"layouts/promo.html":       `{{ define "promo_layout" }}{{ template "product_card" "Featured" }}{{ block "content" . }}{{ end }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "shop/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `<div class="card">Shoes</div>`) {
+		t.Errorf("expected shared define in first page, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.RenderPage(&buf, "promo_layout", "shop/sale.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `<div class="card">Featured</div><div class="card">Hats</div>`) {
+		t.Errorf("expected shared define in layout and second page, got %q", buf.String())
+	}
+
+	err = g.RenderPage(&buf, "app_layout", "_shared/cards.html", nil)
+	if !errors.Is(err, gotemp.ErrPageNotFound) {
+		t.Errorf("expected shared files not to be renderable pages, got %v", err)
+	}
+}
+
+func TestLazyLoad(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html":  `{{ define "content" }}Hello {{ . }}{{ end }}`,
+		"pages/home/broken.html": `{{ define "content" }}{{ if }}{{ end }}`,
+	}), gotemp.WithLazyLoad(true))
+	if err != nil {
+		t.Fatalf("expected lazy load to defer page errors, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	outputs := make([]string, 20)
+	for i := range outputs {
+		wg.Go(func() {
+			var buf bytes.Buffer
+			if err := g.RenderPage(&buf, "app_layout", "home/index.html", "Ada"); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			outputs[i] = buf.String()
+		})
+	}
+	wg.Wait()
+	for _, out := range outputs {
+		if out != "<html><body>Hello Ada</body></html>" {
+			t.Errorf("unexpected concurrent first render output %q", out)
+		}
+	}
+
+	var buf bytes.Buffer
+	err = g.RenderPage(&buf, "app_layout", "home/broken.html", nil)
+	if err == nil || !strings.Contains(err.Error(), "pages/home/broken.html") {
+		t.Errorf("expected parse error on first render, got %v", err)
+	}
+	if _, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/broken.html": `{{ define "content" }}{{ if }}{{ end }}`,
+	})); err == nil {
+		t.Error("expected eager load to fail on the broken page")
+	}
+}
+
+func TestRenderBlock(t *testing.T) {
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderBlock(&buf, "app_layout", "home/index.html", "content", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "Homepage") || strings.Contains(buf.String(), "<!DOCTYPE html>") {
+		t.Errorf("expected only the content block, got %q", buf.String())
+	}
+
+	err = g.RenderBlock(&buf, "app_layout", "home/index.html", "sidebar", nil)
+	if !errors.Is(err, gotemp.ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+	err = g.RenderBlock(&buf, "app_layout", "nonexistent/page.html", "content", nil)
+	if !errors.Is(err, gotemp.ErrPageNotFound) {
+		t.Errorf("expected ErrPageNotFound, got %v", err)
+	}
+}
+
+type fakeRenderer struct {
+	gotemp.Renderer
+	pages []string
+}
+
+func (f *fakeRenderer) RenderPage(w io.Writer, layout, page string, data any) error {
+	f.pages = append(f.pages, layout+":"+page)
+	_, err := io.WriteString(w, "fake")
+	return err
+}
+
+func TestRendererInterface(t *testing.T) {
+	renderHome := func(w io.Writer, renderer gotemp.Renderer) error {
+		return renderer.RenderPage(w, "app_layout", "home/index.html", nil)
+	}
+
+	fake := &fakeRenderer{}
+	var buf bytes.Buffer
+	if err := renderHome(&buf, fake); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "fake" || len(fake.pages) != 1 || fake.pages[0] != "app_layout:home/index.html" {
+		t.Errorf("expected the fake to be used, got %q and %v", buf.String(), fake.pages)
+	}
+
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf.Reset()
+	if err := renderHome(&buf, g); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "Homepage") {
+		t.Error("expected *Gotemp to satisfy Renderer")
+	}
+}
+
+func TestRenderPartialHTML(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"partials/widget.html":  `<div class="widget">{{ . }}</div>`,
+		"pages/home/index.html": `{{ define "content" }}<section>{{ .Widget }}</section>{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	widget, err := g.RenderPartialHTML("widget.html", "<b>Sale</b>")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if widget != `<div class="widget">&lt;b&gt;Sale&lt;/b&gt;</div>` {
+		t.Errorf("expected escaped data inside the partial, got %q", widget)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", map[string]any{"Widget": widget}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `<section><div class="widget">&lt;b&gt;Sale&lt;/b&gt;</div></section>`) {
+		t.Errorf("expected embedded HTML not to be escaped again, got %q", buf.String())
+	}
+
+	if _, err := g.RenderPartialHTML("missing.html", nil); !errors.Is(err, gotemp.ErrPartialNotFound) {
+		t.Errorf("expected ErrPartialNotFound, got %v", err)
+	}
+}
+
+func TestPageKeyFunc(t *testing.T) {
+	stripExt := func(relPath string) string { return strings.TrimSuffix(relPath, ".html") }
+	g, err := gotemp.New("examples", gotemp.WithPageKeyFunc(stripExt))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if got := g.ListPages(); len(got) != 2 || got[0] != "auth/sign_in" || got[1] != "home/index" {
+		t.Errorf("expected keys without extension, got %v", got)
+	}
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/index", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "Homepage") {
+		t.Error("expected 'Homepage' in output")
+	}
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); !errors.Is(err, gotemp.ErrPageNotFound) {
+		t.Errorf("expected the default key to be gone, got %v", err)
+	}
+	buf.Reset()
+	if err := g.RenderRoute(&buf, "app_layout", "/home/index", nil); err != nil || !strings.Contains(buf.String(), "Homepage") {
+		t.Errorf("expected routes to resolve through the key func, got %v", err)
+	}
+
+	_, err = gotemp.New("examples", gotemp.WithPageKeyFunc(func(string) string { return "same" }))
+	if err == nil || !strings.Contains(err.Error(), `both map to key "same"`) {
+		t.Errorf("expected duplicate key error, got %v", err)
+	}
+}
+
+func TestMissingKey(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}[{{ .Missing }}]{{ end }}`,
+	})
+	for _, test := range []struct {
+		mode    gotemp.MissingKey
+		data    any
+		want    string
+		wantErr bool
+	}{
+		{gotemp.MissingKeyDefault, map[string]any{}, "<html><body>[]</body></html>", false},
+		{gotemp.MissingKeyDefault, map[string]int{}, "<html><body>[]</body></html>", false},
+		{gotemp.MissingKeyZero, map[string]any{}, "<html><body>[]</body></html>", false},
+		{gotemp.MissingKeyZero, map[string]int{}, "<html><body>[0]</body></html>", false},
+		{gotemp.MissingKeyError, map[string]any{}, "", true},
+	} {
+		g, err := gotemp.New(dir, gotemp.WithMissingKey(test.mode))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var buf bytes.Buffer
+		err = g.RenderPage(&buf, "app_layout", "home/index.html", test.data)
+		if test.wantErr {
+			if err == nil || !strings.Contains(err.Error(), `map has no entry for key "Missing"`) {
+				t.Errorf("mode %d: expected missing key error, got %v", test.mode, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("mode %d: expected no error, got %v", test.mode, err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("mode %d with %T: expected %q, got %q", test.mode, test.data, test.want, buf.String())
+		}
+	}
+
+	for enabled, want := range map[bool]string{true: "<html><body>[0]</body></html>", false: "<html><body>[]</body></html>"} {
+		g, err := gotemp.New(dir, gotemp.WithMissingKey(gotemp.MissingKeyError), gotemp.WithMissingKeyZero(enabled))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var buf bytes.Buffer
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", map[string]int{}); err != nil {
+			t.Fatalf("WithMissingKeyZero(%t): expected no error, got %v", enabled, err)
+		}
+		if buf.String() != want {
+			t.Errorf("WithMissingKeyZero(%t): expected %q, got %q", enabled, want, buf.String())
+		}
+	}
+}
+
+func TestTemplateOptions(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}[{{ .Missing }}]{{ end }}`,
+		"mail/welcome.txt":      `[{{ .Missing }}]`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithTemplateOptions("missingkey=error"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err = g.RenderPage(io.Discard, "app_layout", "home/index.html", map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), `map has no entry for key "Missing"`) {
+		t.Errorf("expected the option to reach page templates, got %v", err)
+	}
+	if err := g.RenderText(io.Discard, "mail/welcome.txt", map[string]any{}); err == nil {
+		t.Error("expected the option to reach text templates")
+	}
+
+	g, err = gotemp.New(dir, gotemp.WithMissingKey(gotemp.MissingKeyError), gotemp.WithTemplateOptions("missingkey=zero"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.RenderPage(io.Discard, "app_layout", "home/index.html", map[string]any{}); err != nil {
+		t.Errorf("expected template options to apply after WithMissingKey, got %v", err)
+	}
+
+	_, err = gotemp.New(dir, gotemp.WithTemplateOptions("missingkey=zero", "missingkey=maybe"))
+	if err == nil || !strings.Contains(err.Error(), `invalid template option "missingkey=maybe"`) {
+		t.Errorf("expected an invalid option to be reported, got %v", err)
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	files := map[string]string{
+		"partials/_nav.html":     `{{ define "nav" }}{{ if }}{{ end }}`,
+		"layouts/app.html":       `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/home/index.html":  `{{ define "content" }}{{ shout . }}{{ end }}`,
+		"pages/home/about.html":  "---\ntitle\n---\n{{ define \"content\" }}About{{ end }}",
+		"pages/home/health.html": `{{ define "content" }}OK{{ end }}`,
+	}
+
+	_, err := gotemp.New(writeTemplates(t, files))
+	if err == nil || !strings.Contains(err.Error(), "_nav.html") || strings.Contains(err.Error(), "index.html") {
+		t.Errorf("expected fail fast on the first broken file, got %v", err)
+	}
+
+	_, err = gotemp.New(writeTemplates(t, files), gotemp.WithCollectErrors(true))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"partials/_nav.html", `pages/home/index.html: template: index.html:1: function "shout" not defined`, "pages/home/about.html"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the collected errors to mention %q, got %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "health.html") {
+		t.Errorf("expected valid files to be left out, got %v", err)
+	}
+}
+
+func TestSkipBadTemplates(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+		"pages/home/about.html": `{{ define "content" }}About{{ end }}`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithSkipBadTemplates(true), gotemp.WithLogger(slog.New(slog.DiscardHandler)))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("pages/home/about.html", `{{ define "content" }}{{ if }}{{ end }}`)
+	writeFile("pages/home/new.html", `{{ define "content" }}{{ shout }}{{ end }}`)
+	if err := g.Reload(); err != nil {
+		t.Fatalf("expected bad pages to be skipped, got %v", err)
+	}
+
+	for page, want := range map[string]string{"home/index.html": "Home", "home/about.html": "About"} {
+		var buf bytes.Buffer
+		if err := g.RenderPage(&buf, "app_layout", page, nil); err != nil {
+			t.Fatalf("expected %s to render, got %v", page, err)
+		}
+		if buf.String() != "<html><body>"+want+"</body></html>" {
+			t.Errorf("expected %s to render %q, got %q", page, want, buf.String())
+		}
+	}
+	if err := g.RenderPage(io.Discard, "app_layout", "home/new.html", nil); !errors.Is(err, gotemp.ErrPageNotFound) {
+		t.Errorf("expected a bad page without a previous version to be missing, got %v", err)
+	}
+	bad := g.BadTemplates()
+	if len(bad) != 2 || !strings.Contains(errors.Join(bad...).Error(), "about.html") || !strings.Contains(errors.Join(bad...).Error(), "new.html") {
+		t.Errorf("expected both bad pages to be reported, got %v", bad)
+	}
+
+	writeFile("pages/home/about.html", `{{ define "content" }}Fixed{{ end }}`)
+	if err := os.Remove(filepath.Join(dir, "pages/home/new.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Reload(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if bad := g.BadTemplates(); len(bad) != 0 {
+		t.Errorf("expected no bad templates after the fix, got %v", bad)
+	}
+
+	writeFile("pages/home/about.html", `{{ define "content" }}{{ if }}{{ end }}`)
+	if _, err := gotemp.New(dir); err == nil {
+		t.Error("expected a bad page to fail without WithSkipBadTemplates")
+	}
+}
+
+func TestImplicitContent(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html":         `{{ define "app_layout" }}<main>{{ block "content" . }}Default{{ end }}</main>{{ end }}`,
+		"pages/home/explicit.html": `{{ define "content" }}Explicit {{ .Name }}{{ end }}`,
+		"pages/home/implicit.html": "---\ntitle: Implicit\n---\n<h1>{{ .Meta.title }}</h1>{{ if .Name }}<p>{{ .Name }}</p>{{ end }}\n",
+		"pages/home/blocks.html":   `Ignored{{ define "sidebar" }}Side{{ end }}`,
+		"pages/home/blank.html":    "\n  {{/* nothing yet */}}\n",
+		"pages/_shared/note.html":  `Shared text`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := map[string]any{"Name": "<Ada>"}
+	for page, want := range map[string]string{
+		"home/explicit.html": "<main>Explicit &lt;Ada&gt;</main>",
+		"home/implicit.html": "<main><h1>Implicit</h1><p>&lt;Ada&gt;</p></main>",
+		"home/blocks.html":   "<main>Default</main>",
+		"home/blank.html":    "<main>Default</main>",
+	} {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", page, data); err != nil {
+			t.Fatalf("expected %s to render, got %v", page, err)
+		}
+		if buf.String() != want {
+			t.Errorf("expected %s to render %q, got %q", page, want, buf.String())
+		}
+	}
+}
+
+func TestRenderPageBytes(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}Hello {{ .Name }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := map[string]any{"Name": "Ada"}
+	out, err := g.RenderPageBytes("app_layout", "home/index.html", data)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bytes.Equal(out, []byte(buf.String())) {
+		t.Errorf("expected %q, got %q", buf.String(), out)
+	}
+
+	if out, err := g.RenderPageBytes("app_layout", "home/missing.html", nil); !errors.Is(err, gotemp.ErrPageNotFound) || out != nil {
+		t.Errorf("expected ErrPageNotFound and no output, got %q, %v", out, err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRenderPageMulti(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}Hello {{ .Name }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var first, second strings.Builder
+	var third bytes.Buffer
+	if err := g.RenderPageMulti([]io.Writer{&first, &second, &third}, "app_layout", "home/index.html", map[string]any{"Name": "Ada"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "<html><body>Hello Ada</body></html>"
+	if first.String() != want || second.String() != want || third.String() != want {
+		t.Errorf("expected identical output, got %q, %q and %q", first.String(), second.String(), third.String())
+	}
+
+	first.Reset()
+	err = g.RenderPageMulti([]io.Writer{failingWriter{}, &first, failingWriter{}}, "app_layout", "home/index.html", map[string]any{"Name": "Ada"})
+	if err == nil || !strings.Contains(err.Error(), "writer 0: disk full") || !strings.Contains(err.Error(), "writer 2: disk full") {
+		t.Errorf("expected errors for both failing writers, got %v", err)
+	}
+	if first.String() != want {
+		t.Errorf("expected the healthy writer to get the output, got %q", first.String())
+	}
+
+	first.Reset()
+	if err := g.RenderPageMulti([]io.Writer{&first}, "app_layout", "home/missing.html", nil); !errors.Is(err, gotemp.ErrPageNotFound) || first.Len() != 0 {
+		t.Errorf("expected ErrPageNotFound and no output, got %v", err)
+	}
+}
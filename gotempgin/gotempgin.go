@@ -0,0 +1,42 @@
+package gotempgin
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/bllyanos/gotemp"
+)
+
+type Context interface {
+	Data(code int, contentType string, data []byte)
+}
+
+func Render(c Context, g *gotemp.Gotemp, status int, layout, page string, data any) error {
+	var rec recorder
+	if err := g.RenderPageWithStatus(&rec, status, layout, page, data); err != nil {
+		return err
+	}
+	c.Data(rec.status, rec.header.Get("Content-Type"), rec.body.Bytes())
+	return nil
+}
+
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	return r.body.Write(p)
+}
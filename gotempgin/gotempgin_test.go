@@ -0,0 +1,52 @@
+package gotempgin_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bllyanos/gotemp"
+	"github.com/bllyanos/gotemp/gotempgin"
+)
+
+type fakeContext struct {
+	code        int
+	contentType string
+	data        []byte
+}
+
+func (c *fakeContext) Data(code int, contentType string, data []byte) {
+	c.code, c.contentType, c.data = code, contentType, data
+}
+
+func TestRender(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root.html":             {Data: []byte(`{{ define "__start" }}<html><body>{{ end }}{{ define "__end" }}</body></html>{{ end }}`)},
+		"layouts/app.html":      {Data: []byte(`{{ define "app_layout" }}{{ template "__start" . }}{{ block "content" . }}{{ end }}{{ template "__end" . }}{{ end }}`)},
+		"pages/home/index.html": {Data: []byte(`{{ define "content" }}Hello {{ .Name }}{{ end }}`)},
+	}
+	g, err := gotemp.NewFS(fsys, gotemp.WithCharset("UTF-8"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var c fakeContext
+	if err := gotempgin.Render(&c, g, http.StatusCreated, "app_layout", "home/index.html", map[string]any{"Name": "<Ada>"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if c.code != http.StatusCreated || c.contentType != "text/html; charset=UTF-8" {
+		t.Errorf("expected status 201 with the configured charset, got %d %q", c.code, c.contentType)
+	}
+	if string(c.data) != "<html><body>Hello &lt;Ada&gt;</body></html>" {
+		t.Errorf("unexpected body %q", c.data)
+	}
+
+	c = fakeContext{}
+	if err := gotempgin.Render(&c, g, http.StatusOK, "app_layout", "missing.html", nil); !errors.Is(err, gotemp.ErrPageNotFound) {
+		t.Errorf("expected ErrPageNotFound, got %v", err)
+	}
+	if c.data != nil {
+		t.Errorf("expected nothing to be written on error, got %q", c.data)
+	}
+}
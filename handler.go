@@ -0,0 +1,315 @@
+package gotemp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+const StaticPrefix = "/static/"
+
+func (tc *Gotemp) RenderRoute(w io.Writer, layout, route string, data any) error {
+	return tc.RenderPage(w, layout, tc.routePage(route), data)
+}
+
+func (tc *Gotemp) Handler(layout string) http.Handler {
+	return tc.themed(layout, (*Gotemp).handler)
+}
+
+func (tc *Gotemp) handler(layout string) http.Handler {
+	return tc.pageHandler(layout, func(w io.Writer, r *http.Request, layout string) error {
+		page := tc.routePage(r.URL.Path)
+		data, err := tc.loadPageData(r.Context(), page)
+		if err != nil {
+			return err
+		}
+		return tc.renderLayout(w, r, layout, page, data)
+	})
+}
+
+func (tc *Gotemp) HTMXHandler(layout string) http.Handler {
+	return tc.themed(layout, (*Gotemp).htmxHandler)
+}
+
+func (tc *Gotemp) htmxHandler(layout string) http.Handler {
+	return tc.pageHandler(layout, func(w io.Writer, r *http.Request, layout string) error {
+		page := tc.routePage(r.URL.Path)
+		data, err := tc.loadPageData(r.Context(), page)
+		if err != nil {
+			return err
+		}
+		if isHTMXRequest(r) {
+			if target := strings.TrimPrefix(r.Header.Get("HX-Target"), "#"); target != "" {
+				err := tc.renderRequest(w, r, layout, page, target, data)
+				if !errors.Is(err, ErrBlockNotFound) {
+					return err
+				}
+			}
+		}
+		return tc.renderLayout(w, r, layout, page, data)
+	}, "HX-Request", "HX-Target")
+}
+
+func (tc *Gotemp) pageHandler(fullLayout string, render func(io.Writer, *http.Request, string) error, vary ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		for _, header := range vary {
+			w.Header().Add("Vary", header)
+		}
+		layout, bare := fullLayout, false
+		if tc.partialLayout != nil && tc.partialLayout.full == fullLayout {
+			w.Header().Add("Vary", "X-Requested-With")
+			if !slices.Contains(vary, "HX-Request") {
+				w.Header().Add("Vary", "HX-Request")
+			}
+			if isHTMXRequest(r) || r.Header.Get("X-Requested-With") == "XMLHttpRequest" {
+				layout, bare = tc.partialLayout.bare, true
+			}
+		}
+
+		if err := tc.reloadIfChanged(); err != nil {
+			tc.serveError(w, r, layout, err)
+			return
+		}
+		var modTime time.Time
+		var cache, etag string
+		page := tc.routePage(r.URL.Path)
+		if pageEntry := tc.set.Load().pages[page]; pageEntry != nil {
+			cache = pageEntry.cache
+			static := tc.pageData[page] == nil && !tc.perRequest()
+			if static {
+				modTime = pageEntry.modTime
+			}
+			if !bare {
+				layout = pageEntry.frontMatterLayout(layout)
+			}
+			if static && tc.buildID != "" {
+				etag = tc.buildETag(layout, pageEntry.meta.Page)
+				w.Header().Set("ETag", etag)
+			}
+		}
+		if etagMatches(r, etag) || (r.Header.Get("If-None-Match") == "" && notModified(r, modTime)) {
+			setCacheControl(w, cache)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		var buf assetBuffer
+		err := render(&buf, r, layout)
+		if err != nil {
+			tc.serveError(w, r, layout, err)
+			return
+		}
+		if tc.preloadLinks {
+			for _, link := range buf.preloadLinks() {
+				w.Header().Add("Link", link)
+			}
+		}
+
+		if !modTime.IsZero() {
+			w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+		}
+		setCacheControl(w, cache)
+		tc.setContentType(w)
+		tc.writeResponse(w, r, buf.Bytes())
+	})
+}
+
+func (tc *Gotemp) RenderPageWithStatus(w http.ResponseWriter, status int, layout, page string, data any) error {
+	var buf bytes.Buffer
+	if err := tc.RenderPage(&buf, layout, page, data); err != nil {
+		return err
+	}
+	body := buf.Bytes()
+	if len(tc.outputMiddleware) > 0 {
+		var err error
+		if body, err = gunzipped(body); err != nil {
+			return err
+		}
+	}
+	tc.setContentType(w)
+	w.WriteHeader(status)
+	return tc.writeBody(w, body)
+}
+
+func (tc *Gotemp) serveError(w http.ResponseWriter, r *http.Request, layout string, err error) {
+	status, page := http.StatusInternalServerError, tc.serverErrorPage
+	if errors.Is(err, ErrPageNotFound) {
+		status, page = http.StatusNotFound, tc.notFoundPage
+	} else if errors.Is(err, ErrClosed) {
+		status, page = http.StatusServiceUnavailable, ""
+	}
+	if page != "" {
+		data := map[string]any{"Status": status, "Path": r.URL.Path}
+		var buf bytes.Buffer
+		if tc.renderLayout(&buf, r, layout, page, data) == nil {
+			tc.setContentType(w)
+			tc.writeResponseStatus(w, r, status, buf.Bytes())
+			return
+		}
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+func (tc *Gotemp) renderLayout(w io.Writer, r *http.Request, layout, page string, data any) error {
+	if layout == "" {
+		return tc.renderRequest(w, r, "", page, "content", data)
+	}
+	return tc.RenderPageRequest(w, r, layout, page, data)
+}
+
+func setCacheControl(w http.ResponseWriter, cache string) {
+	if cache != "" {
+		w.Header().Set("Cache-Control", cache)
+	}
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+func (tc *Gotemp) setContentType(w http.ResponseWriter) {
+	charset := tc.charset
+	if charset == "" {
+		charset = "utf-8"
+	}
+	w.Header().Set("Content-Type", "text/html; charset="+charset)
+}
+
+func (tc *Gotemp) writeResponse(w http.ResponseWriter, r *http.Request, body []byte) error {
+	return tc.writeResponseStatus(w, r, http.StatusOK, body)
+}
+
+func (tc *Gotemp) writeResponseStatus(w http.ResponseWriter, r *http.Request, status int, body []byte) error {
+	if len(tc.outputMiddleware) == 0 || !isGzip(body) {
+		w.WriteHeader(status)
+		return tc.writeBody(w, body)
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	accepted := acceptsGzip(r)
+	if accepted && !tc.bom {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(status)
+		_, err := w.Write(body)
+		return err
+	}
+	body, err := gunzipped(body)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	if !accepted {
+		w.WriteHeader(status)
+		return tc.writeBody(w, body)
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	w.WriteHeader(status)
+	zw := gzip.NewWriter(w)
+	if err := tc.writeBody(zw, body); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func isGzip(body []byte) bool {
+	return len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b
+}
+
+func gunzipped(body []byte) ([]byte, error) {
+	if !isGzip(body) {
+		return body, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zr)
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, coding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		if name = strings.TrimSpace(name); name != "gzip" && name != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok && strings.Trim(q, "0.") == "" {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func (tc *Gotemp) writeBody(w io.Writer, body []byte) error {
+	if tc.bom && !bytes.HasPrefix(body, utf8BOM) {
+		if _, err := w.Write(utf8BOM); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+func isHTMXRequest(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+func (tc *Gotemp) Mux(layout string, static http.FileSystem) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle(StaticPrefix, http.StripPrefix(StaticPrefix, http.FileServer(static)))
+	mux.Handle("/", tc.Handler(layout))
+	return mux
+}
+
+func (tc *Gotemp) buildETag(layout, page string) string {
+	sum := sha256.Sum256([]byte(tc.buildID + "\x00" + tc.themeName + "\x00" + layout + "\x00" + page))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func etagMatches(r *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func notModified(r *http.Request, modTime time.Time) bool {
+	if modTime.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !modTime.Truncate(time.Second).After(since)
+}
+
+func (tc *Gotemp) routePage(route string) string {
+	dir := route == "" || strings.HasSuffix(route, "/")
+	route = strings.Trim(path.Clean("/"+route), "/")
+	index := tc.pageKey(path.Join(route, "index.html"))
+	if dir {
+		return index
+	}
+	page := tc.pageKey(route + ".html")
+	if pages := tc.set.Load().pages; pages[page] == nil && pages[index] != nil {
+		return index
+	}
+	return page
+}
@@ -0,0 +1,546 @@
+package gotemp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestRenderRoute(t *testing.T) {
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf strings.Builder
+	err = g.RenderRoute(&buf, "app_layout", "/home/index", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "Homepage") {
+		t.Error("expected 'Homepage' in output")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/index", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected text/html content type, got %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "Homepage") {
+		t.Error("expected 'Homepage' in response body")
+	}
+
+	rec = httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nonexistent/page", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestMux(t *testing.T) {
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	mux := g.Mux("app_layout", http.Dir("examples/static"))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/app.css", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for static asset, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "margin") {
+		t.Error("expected stylesheet content in response body")
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/sign_in", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 for page, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "This is login page") {
+		t.Error("expected login page content in response body")
+	}
+}
+
+func TestHandlerLastModified(t *testing.T) {
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	h := g.Handler("app_layout")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/index", nil))
+	lastModified := rec.Header().Get("Last-Modified")
+	if lastModified == "" {
+		t.Fatal("expected Last-Modified header")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/home/index", nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Error("expected empty body for 304 response")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/home/index", nil)
+	req.Header.Set("If-Modified-Since", "Mon, 02 Jan 2006 15:04:05 GMT")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 for stale If-Modified-Since, got %d", rec.Code)
+	}
+
+	for name, opt := range map[string]gotemp.Option{
+		"page data": gotemp.WithPageData("home/index.html", func(ctx context.Context) (any, error) {
+			return nil, nil
+		}),
+		"roles": gotemp.WithRoleProvider(func(r *http.Request) []string { return nil }),
+	} {
+		g, err := gotemp.New("examples", opt)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/home/index", nil)
+		req.Header.Set("If-Modified-Since", lastModified)
+		rec := httptest.NewRecorder()
+		g.Handler("app_layout").ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("Last-Modified") != "" {
+			t.Errorf("%s: expected a fresh render without Last-Modified, got %d %q", name, rec.Code, rec.Header().Get("Last-Modified"))
+		}
+	}
+}
+
+func TestRenderPageWithStatus(t *testing.T) {
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := g.RenderPageWithStatus(rec, http.StatusTeapot, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("expected status 418, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "Homepage") {
+		t.Error("expected 'Homepage' in response body")
+	}
+
+	rec = httptest.NewRecorder()
+	err = g.RenderPageWithStatus(rec, http.StatusOK, "app_layout", "nonexistent/page.html", nil)
+	if err == nil {
+		t.Fatal("expected error for non-existent page")
+	}
+	if rec.Body.Len() != 0 {
+		t.Error("expected nothing written when rendering fails")
+	}
+}
+
+func errorPagesFixture(t *testing.T) string {
+	return writeTemplates(t, map[string]string{
+		"pages/home/index.html":    `{{ define "content" }}Home{{ end }}`,
+		"pages/home/broken.html":   `{{ define "content" }}{{ partial "missing.html" . }}{{ end }}`,
+		"pages/errors/404.html":    `{{ define "content" }}Nothing at {{ .Path }} ({{ .Status }}){{ end }}`,
+		"pages/errors/500.html":    `{{ define "content" }}Something broke ({{ .Status }}){{ end }}`,
+		"pages/errors/broken.html": `{{ define "content" }}{{ partial "missing.html" . }}{{ end }}`,
+	})
+}
+
+func TestHandlerErrorPages(t *testing.T) {
+	g, err := gotemp.New(errorPagesFixture(t), gotemp.WithErrorPages("errors/404.html", "errors/500.html"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	h := g.Handler("app_layout")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nowhere/page", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "Nothing at /nowhere/page (404)") {
+		t.Errorf("expected not found page, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/broken", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "Something broke (500)") {
+		t.Errorf("expected server error page, got %q", rec.Body.String())
+	}
+}
+
+func TestHandlerErrorPageFallback(t *testing.T) {
+	g, err := gotemp.New(errorPagesFixture(t), gotemp.WithErrorPages("errors/404.html", "errors/broken.html"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/broken", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+	if strings.TrimSpace(rec.Body.String()) != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("expected plain text fallback, got %q", rec.Body.String())
+	}
+}
+
+func TestHTMXHandler(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}<ul id="items">{{ block "items" . }}<li>One</li>{{ end }}</ul>{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	h := g.HTMXHandler("app_layout")
+
+	for _, tc := range []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"full page", nil, "<html><body><ul id=\"items\"><li>One</li></ul></body></html>"},
+		{"target without htmx request", map[string]string{"HX-Target": "items"}, "<html><body><ul id=\"items\"><li>One</li></ul></body></html>"},
+		{"htmx request without target", map[string]string{"HX-Request": "true"}, "<html><body><ul id=\"items\"><li>One</li></ul></body></html>"},
+		{"htmx request with unknown target", map[string]string{"HX-Request": "true", "HX-Target": "sidebar"}, "<html><body><ul id=\"items\"><li>One</li></ul></body></html>"},
+		{"htmx request with block target", map[string]string{"HX-Request": "true", "HX-Target": "items"}, "<li>One</li>"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/home/index", nil)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rec.Code)
+			}
+			if rec.Body.String() != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, rec.Body.String())
+			}
+			if vary := rec.Header().Values("Vary"); len(vary) != 2 {
+				t.Errorf("expected Vary on the HTMX headers, got %v", vary)
+			}
+		})
+	}
+}
+
+func TestHandlerPartialLayout(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layouts/bare.html":     `{{ define "bare_layout" }}<div class="bare">{{ block "content" . }}{{ end }}</div>{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+	})
+
+	for _, tc := range []struct {
+		name    string
+		bare    string
+		headers map[string]string
+		want    string
+	}{
+		{"full navigation", "bare_layout", nil, "<html><body>Home</body></html>"},
+		{"fetch request", "bare_layout", map[string]string{"X-Requested-With": "XMLHttpRequest"}, `<div class="bare">Home</div>`},
+		{"htmx request", "bare_layout", map[string]string{"HX-Request": "true"}, `<div class="bare">Home</div>`},
+		{"htmx request without layout", "", map[string]string{"HX-Request": "true"}, "Home"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := gotemp.New(dir, gotemp.WithPartialLayout("app_layout", tc.bare))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/home/index", nil)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			g.Handler("app_layout").ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rec.Code)
+			}
+			if rec.Body.String() != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, rec.Body.String())
+			}
+			if vary := rec.Header().Values("Vary"); len(vary) != 2 {
+				t.Errorf("expected Vary on the partial request headers, got %v", vary)
+			}
+		})
+	}
+
+	g, err := gotemp.New(dir, gotemp.WithPartialLayout("app_layout", "bare_layout"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/home/index", nil)
+	req.Header.Set("HX-Request", "true")
+	rec := httptest.NewRecorder()
+	g.Handler("bare_layout").ServeHTTP(rec, req)
+	if rec.Body.String() != `<div class="bare">Home</div>` || rec.Header().Get("Vary") != "" {
+		t.Errorf("expected other layouts to be left alone, got %q", rec.Body.String())
+	}
+}
+
+func TestRenderRouteIndex(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/blog/index.html": `{{ define "content" }}Blog index{{ end }}`,
+		"pages/blog/first.html": `{{ define "content" }}First post{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for route, want := range map[string]string{
+		"/blog/":       "Blog index",
+		"/blog":        "Blog index",
+		"/blog/index":  "Blog index",
+		"blog/":        "Blog index",
+		"/blog/first":  "First post",
+		"/blog/first/": "",
+		"/":            "",
+	} {
+		var buf strings.Builder
+		err := g.RenderRoute(&buf, "app_layout", route, nil)
+		if want == "" {
+			if !errors.Is(err, gotemp.ErrPageNotFound) {
+				t.Errorf("%s: expected ErrPageNotFound, got %v", route, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", route, err)
+		} else if !strings.Contains(buf.String(), want) {
+			t.Errorf("%s: expected %q, got %q", route, want, buf.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/blog/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Blog index") {
+		t.Errorf("expected handler to serve the index, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandlerFrontMatterLayout(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/wide.html":    `{{ define "wide_layout" }}<div class="wide">{{ block "content" . }}{{ end }}</div>{{ end }}`,
+		"pages/blog/post.html": "---\nlayout: wide_layout\n---\n{{ define \"content\" }}Post{{ end }}",
+	}), gotemp.WithPartialLayout("app_layout", ""))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, test := range []struct {
+		headers map[string]string
+		want    string
+	}{
+		{nil, `<div class="wide">Post</div>`},
+		{map[string]string{"HX-Request": "true"}, "Post"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/blog/post", nil)
+		for name, value := range test.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		g.Handler("app_layout").ServeHTTP(rec, req)
+		if rec.Body.String() != test.want {
+			t.Errorf("headers %v: expected %q, got %q", test.headers, test.want, rec.Body.String())
+		}
+	}
+
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "blog/post.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := `<div class="wide">Post</div>`; buf.String() != want {
+		t.Errorf("expected RenderPage to give the front matter layout the same precedence, got %q", buf.String())
+	}
+}
+
+func TestBOMAndCharset(t *testing.T) {
+	const bom = "\uFEFF"
+	files := map[string]string{
+		"layouts/bom.html":      `{{ define "bom_layout" }}` + bom + `{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}Héllo{{ end }}`,
+	}
+	g, err := gotemp.New(writeTemplates(t, files), gotemp.WithBOM(true), gotemp.WithCharset("UTF-8"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/", nil))
+	if body := rec.Body.String(); body != bom+"<html><body>Héllo</body></html>" {
+		t.Errorf("expected the output to begin with a BOM, got %q", body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=UTF-8" {
+		t.Errorf("expected the configured charset, got %q", ct)
+	}
+
+	rec = httptest.NewRecorder()
+	g.Handler("bom_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/", nil))
+	if body := rec.Body.String(); body != bom+"Héllo" {
+		t.Errorf("expected exactly one BOM when the output already has one, got %q", body)
+	}
+
+	rec = httptest.NewRecorder()
+	if err := g.RenderPageWithStatus(rec, http.StatusAccepted, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if body := rec.Body.String(); body != bom+"<html><body>Héllo</body></html>" {
+		t.Errorf("expected a BOM from RenderPageWithStatus, got %q", body)
+	}
+
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.HasPrefix(buf.String(), bom) {
+		t.Errorf("expected no BOM from RenderPage, got %q", buf.String())
+	}
+
+	g, err = gotemp.New(writeTemplates(t, files))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	rec = httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/", nil))
+	if strings.HasPrefix(rec.Body.String(), bom) || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("expected no BOM and utf-8 by default, got %q %q", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
+
+func TestHandlerCacheControl(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/blog/post.html":   "---\ncache: public\nmax-age: 300\n---\n{{ define \"content\" }}Post{{ end }}",
+		"pages/blog/feed.html":   "---\ncache: no-store\n---\n{{ define \"content\" }}Feed{{ end }}",
+		"pages/blog/static.html": "---\nmax-age: 60\n---\n{{ define \"content\" }}Static{{ end }}",
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for route, want := range map[string]string{
+		"/blog/post":   "public, max-age=300",
+		"/blog/feed":   "no-store",
+		"/blog/static": "max-age=60",
+		"/home/index":  "",
+		"/missing":     "",
+	} {
+		rec := httptest.NewRecorder()
+		g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, route, nil))
+		if got := rec.Header().Get("Cache-Control"); got != want {
+			t.Errorf("%s: expected Cache-Control %q, got %q", route, want, got)
+		}
+	}
+
+	_, err = gotemp.New(writeTemplates(t, map[string]string{
+		"pages/blog/post.html": "---\nmax-age: soon\n---\n{{ define \"content\" }}Post{{ end }}",
+	}))
+	if err == nil || !strings.Contains(err.Error(), "max-age") {
+		t.Errorf("expected invalid max-age to fail, got %v", err)
+	}
+}
+
+func TestHandlerBuildID(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+		"pages/home/about.html": `{{ define "content" }}About{{ end }}`,
+	})
+	etags := func(id string) (http.Handler, map[string]string) {
+		t.Helper()
+		g, err := gotemp.New(dir, gotemp.WithBuildID(id))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		h := g.Handler("app_layout")
+		tags := make(map[string]string)
+		for _, route := range []string{"/home/index", "/home/about"} {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, route, nil))
+			if rec.Code != http.StatusOK || rec.Header().Get("ETag") == "" {
+				t.Fatalf("%s: expected 200 with an ETag, got %d %v", route, rec.Code, rec.Header())
+			}
+			tags[route] = rec.Header().Get("ETag")
+		}
+		return h, tags
+	}
+
+	h, v1 := etags("v1")
+	if v1["/home/index"] == v1["/home/about"] {
+		t.Errorf("expected pages to get distinct ETags, got %v", v1)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/home/index", nil)
+	req.Header.Set("If-None-Match", v1["/home/index"])
+	req.Header.Set("If-Modified-Since", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("expected 304 for a matching ETag, got %d", rec.Code)
+	}
+
+	h, v2 := etags("v2")
+	for route, tag := range v1 {
+		if v2[route] == tag {
+			t.Errorf("%s: expected a new build ID to change the ETag, got %s twice", route, tag)
+		}
+		req := httptest.NewRequest(http.MethodGet, route, nil)
+		req.Header.Set("If-None-Match", tag)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 for an ETag from the previous build, got %d", route, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusNotFound || rec.Header().Get("ETag") != "" {
+		t.Errorf("expected 404 without an ETag, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}{{ if hasRole "admin" }}Settings{{ else }}Guest{{ end }}{{ end }}`,
+	}), gotemp.WithBuildID("v1"), gotemp.WithRoleProvider(func(r *http.Request) []string {
+		return []string{r.Header.Get("X-Role")}
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	rec = httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/", nil))
+	if tag := rec.Header().Get("ETag"); tag != "" {
+		t.Errorf("expected no ETag for a page rendered per request, got %q", tag)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/home/", nil)
+	req.Header.Set("X-Role", "admin")
+	req.Header.Set("If-None-Match", v1["/home/index"])
+	rec = httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Settings") {
+		t.Errorf("expected the admin to get their own render, got %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,145 @@
+package gotemp
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+type LintIssue struct {
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"`
+	Page     string `json:"page,omitempty"`
+	Template string `json:"template,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (tc *Gotemp) Lint() []LintIssue {
+	set := tc.set.Load()
+	var issues []LintIssue
+	referenced := make(map[string]bool)
+	undefined := make(map[string][]string)
+	kinds := make(map[string]string)
+	parsed := 0
+	for _, name := range tc.ListPages() {
+		pageEntry := set.pages[name]
+		if err := pageEntry.ready(); err != nil {
+			issues = append(issues, LintIssue{Severity: "error", Kind: "parse", Page: name, Message: err.Error()})
+			continue
+		}
+		parsed++
+		sets := []*template.Template{pageEntry.template}
+		for _, scoped := range pageEntry.scoped {
+			if !slices.Contains(sets, scoped) {
+				sets = append(sets, scoped)
+			}
+		}
+		seen := make(map[string]bool)
+		for _, t := range sets {
+			for _, ref := range templateRefs(t, set.partials) {
+				if ref.name == "" {
+					continue
+				}
+				visible, _ := pageEntry.visibleName(ref.name)
+				referenced[visible] = true
+				if t.Lookup(ref.name) == nil && !seen[visible] {
+					seen[visible] = true
+					undefined[visible] = append(undefined[visible], name)
+					kinds[visible] = ref.kind
+				}
+			}
+		}
+	}
+
+	names := slices.Sorted(maps.Keys(undefined))
+	for _, missing := range names {
+		pages := undefined[missing]
+		if parsed > 1 && len(pages) == parsed {
+			issues = append(issues, LintIssue{Severity: "error", Kind: "undefined", Template: missing,
+				Message: fmt.Sprintf("%s %q is not defined in any page", kinds[missing], missing)})
+			continue
+		}
+		for _, page := range pages {
+			issues = append(issues, LintIssue{Severity: "error", Kind: "undefined", Page: page, Template: missing,
+				Message: fmt.Sprintf("%s %q is not defined", kinds[missing], missing)})
+		}
+	}
+
+	files, err := tc.partialFiles()
+	if err != nil {
+		return append(issues, LintIssue{Severity: "error", Kind: "parse", Message: err.Error()})
+	}
+	for _, file := range files {
+		content, err := fs.ReadFile(tc.fsys, file)
+		if err != nil {
+			issues = append(issues, LintIssue{Severity: "error", Kind: "parse", Message: err.Error()})
+			continue
+		}
+		name := strings.TrimPrefix(file, "partials/")
+		treeSet, err := parseTrees(name, string(content))
+		if err != nil {
+			continue
+		}
+		var defined []string
+		for treeName, tree := range treeSet {
+			if !parse.IsEmptyTree(tree.Root) && !referenced[treeName] {
+				defined = append(defined, treeName)
+			}
+		}
+		sort.Strings(defined)
+		for _, treeName := range defined {
+			issues = append(issues, LintIssue{Severity: "warning", Kind: "orphan", Template: treeName,
+				Message: fmt.Sprintf("%s defines %q, which no page or layout uses", file, treeName)})
+		}
+	}
+	return issues
+}
+
+type templateRef struct {
+	kind string
+	name string
+}
+
+func templateRefs(t *template.Template, partials map[string]string) []templateRef {
+	var refs []templateRef
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			refs = append(refs, treeRefs(tmpl.Tree, partials)...)
+		}
+	}
+	return refs
+}
+
+func treeRefs(tree *parse.Tree, partials map[string]string) []templateRef {
+	var refs []templateRef
+	walkNodes(tree.Root, func(node parse.Node) {
+		switch node := node.(type) {
+		case *parse.TemplateNode:
+			refs = append(refs, templateRef{"template", node.Name})
+		case *parse.CommandNode:
+			if len(node.Args) < 2 {
+				return
+			}
+			ident, ok := node.Args[0].(*parse.IdentifierNode)
+			if !ok || (ident.Ident != "partial" && ident.Ident != "cachedPartial") {
+				return
+			}
+			name, ok := node.Args[1].(*parse.StringNode)
+			if !ok {
+				refs = append(refs, templateRef{"partial", ""})
+				return
+			}
+			entrypoint := name.Text
+			if resolved, ok := partials[entrypoint]; ok {
+				entrypoint = resolved
+			}
+			refs = append(refs, templateRef{"partial", entrypoint})
+		}
+	})
+	return refs
+}
@@ -0,0 +1,49 @@
+package gotemp_test
+
+import (
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestLint(t *testing.T) {
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, issue := range g.Lint() {
+		if issue.Severity == "error" {
+			t.Errorf("expected no errors for the examples, got %+v", issue)
+		}
+	}
+
+	g, err = gotemp.New(writeTemplates(t, map[string]string{
+		"partials/_widgets.html": `{{ define "used_widget" }}used{{ end }}{{ define "unused_widget" }}unused{{ end }}`,
+		"pages/home/index.html":  `{{ define "content" }}{{ template "used_widget" . }}{{ template "sidebar" . }}{{ partial "missing.html" . }}{{ end }}`,
+		"pages/home/about.html":  `{{ define "content" }}{{ template "used_widget" . }}{{ end }}`,
+		"pages/home/broken.html": `{{ define "content" }}{{ if }}{{ end }}`,
+	}), gotemp.WithLazyLoad(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := []gotemp.LintIssue{
+		{Severity: "error", Kind: "parse", Page: "home/broken.html"},
+		{Severity: "error", Kind: "undefined", Page: "home/index.html", Template: "missing.html"},
+		{Severity: "error", Kind: "undefined", Page: "home/index.html", Template: "sidebar"},
+		{Severity: "warning", Kind: "orphan", Template: "unused_widget"},
+	}
+	issues := g.Lint()
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %+v", len(want), issues)
+	}
+	for i, issue := range issues {
+		if issue.Message == "" {
+			t.Errorf("expected a message for %+v", issue)
+		}
+		issue.Message = ""
+		if issue != want[i] {
+			t.Errorf("issue %d: expected %+v, got %+v", i, want[i], issue)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package gotemp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+)
+
+const (
+	listFunc      = "listItems"
+	listMarker    = "<!--gotemp:list-items-->"
+	listFlushRows = 100
+)
+
+var errNoListItems = errors.New("shell does not call " + listFunc)
+
+func listItems() template.HTML {
+	return listMarker
+}
+
+func (tc *Gotemp) RenderList(w io.Writer, layout, page, itemTemplate string, items []any, data any) error {
+	if err := tc.reloadIfChanged(); err != nil {
+		return err
+	}
+	set := tc.set.Load()
+	pageEntry := set.pages[page]
+	if pageEntry == nil {
+		return fmt.Errorf("%w: %s", ErrPageNotFound, page)
+	}
+	if err := pageEntry.ready(); err != nil {
+		return err
+	}
+	layout = tc.pageLayout(pageEntry, layout)
+	shellData, err := tc.renderData(pageEntry, data)
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	t, err := tc.renderTemplate(pageEntry, layout, set.partials)
+	if err != nil {
+		return err
+	}
+	row := pageEntry.entry(t, itemTemplate)
+	if t.Lookup(row) == nil {
+		return fmt.Errorf("%w: %s in page %s", ErrBlockNotFound, itemTemplate, page)
+	}
+
+	var shell bytes.Buffer
+	if err := tc.execute(&shell, t, pageEntry.entry(t, layout), shellData, false); err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	head, tail, found := bytes.Cut(shell.Bytes(), []byte(listMarker))
+	if !found {
+		return fmt.Errorf("page %s: %w", page, errNoListItems)
+	}
+
+	out, closers := tc.pageWriter(w)
+	if tc.maxOutput > 0 {
+		out = &limitWriter{w: out, remaining: tc.maxOutput, limit: tc.maxOutput}
+	}
+	bw := bufio.NewWriter(out)
+	flush := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		for i := len(closers) - 1; i >= 0; i-- {
+			if flusher, ok := closers[i].(interface{ Flush() error }); ok {
+				if err := flusher.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+	if _, err := bw.Write(head); err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	for i, item := range items {
+		if err := tc.execute(bw, t, row, item, false); err != nil {
+			return fmt.Errorf("page %s item %d: %w", page, i, err)
+		}
+		if (i+1)%listFlushRows == 0 {
+			if err := flush(); err != nil {
+				return fmt.Errorf("page %s: %w", page, err)
+			}
+		}
+	}
+	if _, err := bw.Write(tail); err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil {
+			return err
+		}
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
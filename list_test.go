@@ -0,0 +1,118 @@
+package gotemp_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+type watchWriter struct {
+	total   int
+	largest int
+	rows    int
+	flushes int
+}
+
+func (w *watchWriter) Write(p []byte) (int, error) {
+	w.total += len(p)
+	w.largest = max(w.largest, len(p))
+	w.rows += strings.Count(string(p), "<tr>")
+	return len(p), nil
+}
+
+func (w *watchWriter) Flush() {
+	w.flushes++
+}
+
+func TestRenderList(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/users.html": `{{ define "content" }}<table>{{ listItems }}</table>{{ end }}` +
+			`{{ define "row" }}<tr><td>{{ .Name }}</td></tr>{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}{{ define "row" }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf strings.Builder
+	items := []any{map[string]any{"Name": "Ada"}, map[string]any{"Name": "<Bob>"}}
+	if err := g.RenderList(&buf, "app_layout", "home/users.html", "row", items, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "<html><body><table><tr><td>Ada</td></tr><tr><td>&lt;Bob&gt;</td></tr></table></body></html>"; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	const count = 100_000
+	items = make([]any, count)
+	for i := range items {
+		items[i] = map[string]any{"Name": fmt.Sprintf("user-%d", i)}
+	}
+	w := &watchWriter{}
+	if err := g.RenderList(w, "app_layout", "home/users.html", "row", items, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if w.rows != count {
+		t.Errorf("expected %d rows, got %d", count, w.rows)
+	}
+	if w.largest > 8<<10 {
+		t.Errorf("expected the output to stream in small writes, got a %d byte write of %d", w.largest, w.total)
+	}
+	if w.flushes < count/100 {
+		t.Errorf("expected a flush every 100 rows, got %d flushes", w.flushes)
+	}
+
+	if err := g.RenderList(&buf, "app_layout", "home/users.html", "missing", items, nil); !errors.Is(err, gotemp.ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+	if err := g.RenderList(&buf, "app_layout", "home/index.html", "row", items, nil); err == nil || !strings.Contains(err.Error(), "listItems") {
+		t.Errorf("expected an error for a shell without listItems, got %v", err)
+	}
+	if err := g.RenderList(&buf, "app_layout", "home/users.html", "row", []any{42}, nil); err == nil || !strings.Contains(err.Error(), "item 0") {
+		t.Errorf("expected the failing item in the error, got %v", err)
+	}
+}
+
+func TestRenderListPipeline(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/users.html": `{{ define "content" }}<h1>{{ .Title }}</h1><ul>{{ listItems }}</ul>{{ emitCSS }}{{ end }}` +
+			`{{ define "row" }}{{ requireCSS "/row.css" }}<li>{{ . }}</li>{{ end }}`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithOutputMiddleware(gotemp.Gzip))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := g.RenderList(&buf, "app_layout", "home/users.html", "row", []any{"a", "b"}, map[string]any{"Title": "Users"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("expected one gzip stream, got %v", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("expected a complete gzip stream, got %v", err)
+	}
+	if want := "<html><body><h1>Users</h1><ul><li>a</li><li>b</li></ul></body></html>"; string(out) != want {
+		t.Errorf("expected %q, got %q", want, out)
+	}
+
+	g, err = gotemp.New(dir, gotemp.WithMaxOutputBytes(64))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	items := make([]any, 100)
+	for i := range items {
+		items[i] = "row"
+	}
+	if err := g.RenderList(io.Discard, "app_layout", "home/users.html", "row", items, nil); !errors.Is(err, gotemp.ErrOutputTooLarge) {
+		t.Errorf("expected the size limit to cover the rows, got %v", err)
+	}
+}
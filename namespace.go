@@ -0,0 +1,108 @@
+package gotemp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+)
+
+func (tc *Gotemp) RegisterNamespace(name, basePath string) error {
+	if name == "" || strings.ContainsAny(name, ":/") {
+		return fmt.Errorf("invalid namespace name %q", name)
+	}
+	tc.namespacesMu.Lock()
+	defer tc.namespacesMu.Unlock()
+	if _, ok := tc.namespaces[name]; ok {
+		return fmt.Errorf("namespace %s: %w", name, fs.ErrExist)
+	}
+	opts := append(slices.Clone(tc.opts), withNamespaceName(name))
+	engine, err := newGotemp(basePath, overlayFS{upper: os.DirFS(basePath), lower: sharedOnlyFS{fsys: tc.fsys}}, opts)
+	if err != nil {
+		return fmt.Errorf("namespace %s: %w", name, err)
+	}
+	if tc.namespaces == nil {
+		tc.namespaces = make(map[string]*Gotemp)
+	}
+	tc.namespaces[name] = engine
+	return nil
+}
+
+func (tc *Gotemp) RenderNamespace(w io.Writer, namespace, layout, page string, data any) error {
+	engine := tc.namespace(namespace)
+	if engine == nil {
+		return fmt.Errorf("%w: %s", ErrNamespaceNotFound, namespace)
+	}
+	return engine.RenderPage(w, layout, page, data)
+}
+
+func (tc *Gotemp) namespace(name string) *Gotemp {
+	tc.namespacesMu.RLock()
+	defer tc.namespacesMu.RUnlock()
+	return tc.namespaces[name]
+}
+
+func (tc *Gotemp) namespacedPage(page string) (*Gotemp, string) {
+	name, nsPage, ok := strings.Cut(page, ":")
+	if !ok {
+		return nil, ""
+	}
+	return tc.namespace(name), nsPage
+}
+
+func (tc *Gotemp) forEngines(apply func(*Gotemp) error) error {
+	var errs []error
+	for name, engine := range tc.themes {
+		if err := apply(engine); err != nil {
+			errs = append(errs, fmt.Errorf("theme %s: %w", name, err))
+		}
+	}
+	tc.namespacesMu.RLock()
+	defer tc.namespacesMu.RUnlock()
+	for name, engine := range tc.namespaces {
+		if err := apply(engine); err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type sharedOnlyFS struct {
+	fsys fs.FS
+}
+
+func (s sharedOnlyFS) Open(name string) (fs.File, error) {
+	if hiddenPage(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return s.fsys.Open(name)
+}
+
+func (s sharedOnlyFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if hiddenPage(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err != nil || name != "pages" {
+		return entries, err
+	}
+	var shared []fs.DirEntry
+	for _, entry := range entries {
+		if entry.IsDir() && isSharedDir(entry.Name()) {
+			shared = append(shared, entry)
+		}
+	}
+	return shared, nil
+}
+
+func hiddenPage(name string) bool {
+	rest, ok := strings.CutPrefix(name, "pages/")
+	if !ok {
+		return false
+	}
+	dir, _, _ := strings.Cut(rest, "/")
+	return !isSharedDir(dir)
+}
@@ -0,0 +1,88 @@
+package gotemp_test
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestNamespaces(t *testing.T) {
+	layout := `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ template "footer" . }}{{ end }}`
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html":       layout,
+		"partials/_footer.html":  `{{ define "footer" }}<footer>Shared</footer>{{ end }}`,
+		"partials/_badge.html":   `{{ define "badge" }}<b>Base</b>{{ end }}`,
+		"pages/home/index.html":  `{{ define "content" }}Base home{{ end }}`,
+		"pages/home/about.html":  `{{ define "content" }}Base about{{ end }}`,
+		"pages/_shared/cta.html": `{{ define "cta" }}<a>Sign up</a>{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	shop := writeTemplates(t, map[string]string{
+		"layouts/app.html":      layout,
+		"pages/home/index.html": `{{ define "content" }}Shop home {{ template "badge" . }}{{ template "cta" . }}{{ end }}`,
+		"partials/_badge.html":  `{{ define "badge" }}<b>Shop</b>{{ end }}`,
+	})
+	blog := writeTemplates(t, map[string]string{
+		"layouts/app.html":      layout,
+		"pages/home/index.html": `{{ define "content" }}Blog home {{ template "badge" . }}{{ end }}`,
+	})
+	if err := g.RegisterNamespace("shop", shop); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.RegisterNamespace("blog", blog); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, test := range []struct {
+		namespace, page, want string
+	}{
+		{"shop", "home/index.html", "Shop home <b>Shop</b><a>Sign up</a><footer>Shared</footer>"},
+		{"blog", "home/index.html", "Blog home <b>Base</b><footer>Shared</footer>"},
+	} {
+		var buf strings.Builder
+		if err := g.RenderNamespace(&buf, test.namespace, "app_layout", test.page, nil); err != nil {
+			t.Fatalf("%s: expected no error, got %v", test.namespace, err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("%s: expected %q, got %q", test.namespace, test.want, buf.String())
+		}
+
+		buf.Reset()
+		if err := g.RenderPage(&buf, "app_layout", test.namespace+":"+test.page, nil); err != nil || buf.String() != test.want {
+			t.Errorf("%s: expected namespace:page addressing, got %q, %v", test.namespace, buf.String(), err)
+		}
+	}
+
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil || buf.String() != "Base home<footer>Shared</footer>" {
+		t.Errorf("expected base pages to be unaffected, got %q, %v", buf.String(), err)
+	}
+	if err := g.RenderNamespace(&buf, "shop", "app_layout", "home/about.html", nil); !errors.Is(err, gotemp.ErrPageNotFound) {
+		t.Errorf("expected base pages to stay out of namespaces, got %v", err)
+	}
+	if err := g.RenderNamespace(&buf, "admin", "app_layout", "home/index.html", nil); !errors.Is(err, gotemp.ErrNamespaceNotFound) {
+		t.Errorf("expected ErrNamespaceNotFound, got %v", err)
+	}
+	if err := g.RenderPage(&buf, "app_layout", "admin:home/index.html", nil); !errors.Is(err, gotemp.ErrPageNotFound) {
+		t.Errorf("expected ErrPageNotFound for an unknown namespace, got %v", err)
+	}
+	if err := g.RegisterNamespace("shop", blog); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected fs.ErrExist for a duplicate namespace, got %v", err)
+	}
+	if err := g.RegisterNamespace("a:b", blog); err == nil {
+		t.Error("expected an error for an invalid namespace name")
+	}
+
+	if err := g.UpdateTemplate("partials/_footer.html", `{{ define "footer" }}<footer>Edited</footer>{{ end }}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf.Reset()
+	if err := g.RenderNamespace(&buf, "blog", "app_layout", "home/index.html", nil); err != nil || buf.String() != "Blog home <b>Base</b><footer>Edited</footer>" {
+		t.Errorf("expected UpdateTemplate to reach namespaces, got %q, %v", buf.String(), err)
+	}
+}
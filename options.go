@@ -0,0 +1,444 @@
+package gotemp
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+type Option func(*Gotemp)
+
+func WithOptionalPages(optional bool) Option {
+	return func(tc *Gotemp) {
+		tc.optionalPages = optional
+	}
+}
+
+func WithTypeFormatter(example any, format func(any) string) Option {
+	return func(tc *Gotemp) {
+		if tc.formatters == nil {
+			tc.formatters = make(map[reflect.Type]func(any) string)
+		}
+		tc.formatters[reflect.TypeOf(example)] = format
+	}
+}
+
+func WithErrorPages(notFound, serverError string) Option {
+	return func(tc *Gotemp) {
+		tc.notFoundPage = notFound
+		tc.serverErrorPage = serverError
+	}
+}
+
+func WithTrimActions(trim bool) Option {
+	return func(tc *Gotemp) {
+		tc.trimActions = trim
+	}
+}
+
+func WithAMPTransform(amp bool) Option {
+	return func(tc *Gotemp) {
+		tc.ampTransform = amp
+	}
+}
+
+func WithMaxOutputBytes(n int64) Option {
+	return func(tc *Gotemp) {
+		tc.maxOutput = n
+	}
+}
+
+func WithMaxIncludeDepth(n int) Option {
+	return func(tc *Gotemp) {
+		tc.maxIncludeDepth = n
+	}
+}
+
+func WithRenderCache(size int) Option {
+	return func(tc *Gotemp) {
+		tc.renderCache = nil
+		if size > 0 {
+			tc.renderCache = newRenderCache(size)
+		}
+	}
+}
+
+func WithRenderCacheBackend(backend Cache) Option {
+	return func(tc *Gotemp) {
+		tc.renderCache = nil
+		if backend != nil {
+			tc.renderCache = newBackendRenderCache(backend)
+		}
+	}
+}
+
+func WithCacheTTL(d time.Duration) Option {
+	return func(tc *Gotemp) {
+		tc.cacheTTL = d
+	}
+}
+
+func WithPageCacheTTL(page string, d time.Duration) Option {
+	return func(tc *Gotemp) {
+		if tc.pageCacheTTL == nil {
+			tc.pageCacheTTL = make(map[string]time.Duration)
+		}
+		tc.pageCacheTTL[page] = d
+	}
+}
+
+func WithLazyLoad(lazy bool) Option {
+	return func(tc *Gotemp) {
+		tc.lazyLoad = lazy
+	}
+}
+
+func WithCollectErrors(collect bool) Option {
+	return func(tc *Gotemp) {
+		tc.collectErrors = collect
+	}
+}
+
+func WithSkipBadTemplates(skip bool) Option {
+	return func(tc *Gotemp) {
+		tc.skipBadTemplates = skip
+	}
+}
+
+func WithPreloadLinks(enabled bool) Option {
+	return func(tc *Gotemp) {
+		tc.preloadLinks = enabled
+	}
+}
+
+func WithStrictDefines(strict bool) Option {
+	return func(tc *Gotemp) {
+		tc.strictDefines = strict
+	}
+}
+
+func WithEnforceLayoutContract(enforce bool) Option {
+	return func(tc *Gotemp) {
+		tc.layoutContract = enforce
+	}
+}
+
+func WithEnv(name string) Option {
+	return func(tc *Gotemp) {
+		tc.env = name
+	}
+}
+
+func WithJSONFieldMapping(enabled bool) Option {
+	return func(tc *Gotemp) {
+		tc.jsonFields = enabled
+	}
+}
+
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(tc *Gotemp) {
+		custom := maps.Clone(funcs)
+		if previous := tc.customFuncs.Load(); previous != nil {
+			custom = maps.Clone(*previous)
+			maps.Copy(custom, funcs)
+		}
+		tc.customFuncs.Store(&custom)
+	}
+}
+
+func WithCurrentPageField(enabled bool) Option {
+	return func(tc *Gotemp) {
+		tc.currentPageField = enabled
+	}
+}
+
+func WithBOM(enabled bool) Option {
+	return func(tc *Gotemp) {
+		tc.bom = enabled
+	}
+}
+
+func WithCharset(charset string) Option {
+	return func(tc *Gotemp) {
+		tc.charset = charset
+	}
+}
+
+func WithSitemapExclude(patterns ...string) Option {
+	return func(tc *Gotemp) {
+		tc.sitemapExclude = append(tc.sitemapExclude, patterns...)
+	}
+}
+
+func WithRequestHelpers(enabled bool) Option {
+	return func(tc *Gotemp) {
+		tc.requestHelpers = enabled
+	}
+}
+
+func WithFlagsProvider(provider func(r *http.Request) map[string]bool) Option {
+	return func(tc *Gotemp) {
+		tc.flags = provider
+	}
+}
+
+func WithRoleProvider(provider func(r *http.Request) []string) Option {
+	return func(tc *Gotemp) {
+		tc.roles = provider
+	}
+}
+
+func WithBuildID(id string) Option {
+	return func(tc *Gotemp) {
+		tc.buildID = id
+	}
+}
+
+func withThemeName(name string) Option {
+	return func(tc *Gotemp) {
+		tc.themeName = name
+	}
+}
+
+func withNamespaceName(name string) Option {
+	return func(tc *Gotemp) {
+		tc.namespaceName = name
+	}
+}
+
+func WithThemeSelector(selector func(r *http.Request) string) Option {
+	return func(tc *Gotemp) {
+		tc.themeSelector = selector
+	}
+}
+
+func WithTruncationMarker(fragment string) Option {
+	return func(tc *Gotemp) {
+		tc.truncationMarker = fragment
+	}
+}
+
+func WithBuildStamp(version string) Option {
+	return func(tc *Gotemp) {
+		tc.buildStamp = version
+	}
+}
+
+func WithTransformer(name string, transform func(any) (any, error)) Option {
+	return func(tc *Gotemp) {
+		if tc.transformers == nil {
+			tc.transformers = make(map[string]func(any) (any, error))
+		}
+		tc.transformers[name] = transform
+	}
+}
+
+func WithLineNumbers(enabled bool) Option {
+	return func(tc *Gotemp) {
+		tc.lineNumbers = enabled
+	}
+}
+
+func WithIncludeDrafts(include bool) Option {
+	return func(tc *Gotemp) {
+		tc.includeDrafts = include
+	}
+}
+
+func WithRemoteIncludes(client *http.Client, cacheTTL time.Duration, hosts ...string) Option {
+	return func(tc *Gotemp) {
+		tc.remote = newRemoteIncludes(client, cacheTTL, hosts)
+	}
+}
+
+func WithPartialLayout(fullLayout, bareLayout string) Option {
+	return func(tc *Gotemp) {
+		tc.partialLayout = &partialLayout{full: fullLayout, bare: bareLayout}
+	}
+}
+
+func WithPageKeyFunc(key func(relPath string) string) Option {
+	return func(tc *Gotemp) {
+		tc.pageKeyFunc = key
+	}
+}
+
+func WithReloadStrategy(strategy ReloadStrategy) Option {
+	return func(tc *Gotemp) {
+		tc.reloadStrategy = strategy
+	}
+}
+
+func WithPollReload(interval time.Duration) Option {
+	return func(tc *Gotemp) {
+		tc.pollInterval = interval
+	}
+}
+
+func WithProfiling(enabled bool) Option {
+	return func(tc *Gotemp) {
+		tc.profiling = enabled
+	}
+}
+
+func WithEscapeDebug(enabled bool) Option {
+	return func(tc *Gotemp) {
+		tc.escapeDebug = enabled
+	}
+}
+
+func WithErrorDataContext(enabled bool) Option {
+	return func(tc *Gotemp) {
+		tc.errorDataContext = enabled
+	}
+}
+
+func WithRedactKeys(keys ...string) Option {
+	return func(tc *Gotemp) {
+		tc.redactKeys = append(tc.redactKeys, keys...)
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(tc *Gotemp) {
+		tc.log = logger
+	}
+}
+
+func WithPageData(page string, loader PageLoader) Option {
+	return func(tc *Gotemp) {
+		if tc.pageData == nil {
+			tc.pageData = make(map[string]PageLoader)
+		}
+		tc.pageData[page] = loader
+	}
+}
+
+func WithPartialCache(name string, ttl time.Duration) Option {
+	return func(tc *Gotemp) {
+		if tc.partialCache == nil {
+			tc.partialCache = newRenderCache(partialCacheSize)
+			tc.partialCacheTTL = make(map[string]time.Duration)
+		}
+		tc.partialCacheTTL[name] = ttl
+	}
+}
+
+func WithSharedTemplates(shared bool) Option {
+	return func(tc *Gotemp) {
+		tc.sharedTemplates = shared
+	}
+}
+
+func WithDefaultLayout(layout string) Option {
+	return func(tc *Gotemp) {
+		tc.defaultLayout = layout
+	}
+}
+
+func WithAlwaysInclude(globs ...string) Option {
+	return func(tc *Gotemp) {
+		tc.alwaysInclude = append(tc.alwaysInclude, globs...)
+	}
+}
+
+func WithExtraDefineGlobs(globs ...string) Option {
+	return func(tc *Gotemp) {
+		tc.extraDefineGlobs = append(tc.extraDefineGlobs, globs...)
+	}
+}
+
+func WithOutputMiddleware(mw ...func(io.Writer) io.Writer) Option {
+	return func(tc *Gotemp) {
+		tc.outputMiddleware = append(tc.outputMiddleware, mw...)
+	}
+}
+
+func WithBaseData(data map[string]any) Option {
+	return func(tc *Gotemp) {
+		tc.baseData = data
+	}
+}
+
+func WithPageDefaults(page string, defaults map[string]any) Option {
+	return func(tc *Gotemp) {
+		if tc.pageDataDefaults == nil {
+			tc.pageDataDefaults = make(map[string]map[string]any)
+		}
+		merged := maps.Clone(tc.pageDataDefaults[page])
+		if merged == nil {
+			merged = make(map[string]any, len(defaults))
+		}
+		maps.Copy(merged, defaults)
+		tc.pageDataDefaults[page] = merged
+	}
+}
+
+func WithTrustedFields(page string, fields ...string) Option {
+	return func(tc *Gotemp) {
+		if tc.trustedFields == nil {
+			tc.trustedFields = make(map[string][]string)
+		}
+		tc.trustedFields[page] = append(tc.trustedFields[page], fields...)
+	}
+}
+
+type MissingKey int
+
+const (
+	MissingKeyDefault MissingKey = iota
+	MissingKeyZero
+	MissingKeyError
+)
+
+func (m MissingKey) option() string {
+	switch m {
+	case MissingKeyZero:
+		return "missingkey=zero"
+	case MissingKeyError:
+		return "missingkey=error"
+	default:
+		return "missingkey=default"
+	}
+}
+
+func WithMissingKey(mode MissingKey) Option {
+	return func(tc *Gotemp) {
+		tc.missingKey = mode
+	}
+}
+
+func WithMissingKeyZero(enabled bool) Option {
+	if enabled {
+		return WithMissingKey(MissingKeyZero)
+	}
+	return WithMissingKey(MissingKeyDefault)
+}
+
+func WithTemplateOptions(opts ...string) Option {
+	return func(tc *Gotemp) {
+		tc.templateOptions = append(tc.templateOptions, opts...)
+	}
+}
+
+func (tc *Gotemp) parseOptions() []string {
+	return append([]string{tc.missingKey.option()}, tc.templateOptions...)
+}
+
+func checkTemplateOptions(opts []string) (err error) {
+	var opt string
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid template option %q: %v", opt, r)
+		}
+	}()
+	for _, opt = range opts {
+		template.New("options").Option(opt)
+	}
+	return nil
+}
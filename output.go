@@ -0,0 +1,190 @@
+package gotemp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+type limitWriter struct {
+	w         io.Writer
+	remaining int64
+	limit     int64
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > lw.remaining {
+		lw.remaining = 0
+		return 0, fmt.Errorf("%w of %d bytes", ErrOutputTooLarge, lw.limit)
+	}
+	lw.remaining -= int64(len(p))
+	return lw.w.Write(p)
+}
+
+const bodyClose = "</body>"
+
+func stampComment(version string) string {
+	return fmt.Sprintf("<!-- built: %s at %s -->", version, time.Now().UTC().Format(time.RFC3339))
+}
+
+type stampWriter struct {
+	w       io.Writer
+	stamp   string
+	pending []byte
+	done    bool
+}
+
+func (sw *stampWriter) Write(p []byte) (int, error) {
+	if sw.done {
+		return sw.w.Write(p)
+	}
+	sw.pending = append(sw.pending, p...)
+	if i := indexFold(sw.pending, bodyClose); i >= 0 {
+		sw.done = true
+		out := slices.Concat(sw.pending[:i], []byte(sw.stamp), sw.pending[i:])
+		sw.pending = nil
+		if _, err := sw.w.Write(out); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if keep := len(bodyClose) - 1; len(sw.pending) > keep {
+		flush := len(sw.pending) - keep
+		if _, err := sw.w.Write(sw.pending[:flush]); err != nil {
+			return 0, err
+		}
+		sw.pending = append(sw.pending[:0], sw.pending[flush:]...)
+	}
+	return len(p), nil
+}
+
+func (sw *stampWriter) Close() error {
+	if sw.done {
+		return nil
+	}
+	sw.done = true
+	_, err := sw.w.Write(append(sw.pending, sw.stamp...))
+	return err
+}
+
+func indexFold(s []byte, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if bytes.EqualFold(s[i:i+len(substr)], []byte(substr)) {
+			return i
+		}
+	}
+	return -1
+}
+
+type trimWriter struct {
+	w    io.Writer
+	line []byte
+}
+
+func (tw *trimWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			tw.line = append(tw.line, p...)
+			break
+		}
+		tw.line = append(tw.line, p[:i+1]...)
+		p = p[i+1:]
+		if err := tw.flushLine(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func (tw *trimWriter) Close() error {
+	return tw.flushLine()
+}
+
+func (tw *trimWriter) flushLine() error {
+	defer func() { tw.line = tw.line[:0] }()
+	if len(bytes.TrimSpace(tw.line)) == 0 {
+		return nil
+	}
+	_, err := tw.w.Write(tw.line)
+	return err
+}
+
+func Gzip(w io.Writer) io.Writer {
+	return gzip.NewWriter(w)
+}
+
+func Minify(w io.Writer) io.Writer {
+	return &minifyWriter{w: w}
+}
+
+type minifyWriter struct {
+	w       io.Writer
+	out     []byte
+	tag     []byte
+	reading bool
+	raw     string
+	space   byte
+}
+
+func (mw *minifyWriter) Write(p []byte) (int, error) {
+	for _, c := range p {
+		if isSpace(c) {
+			mw.endTag()
+			if mw.raw != "" {
+				mw.out = append(mw.out, c)
+			} else if c == '\n' {
+				mw.space = '\n'
+			} else if mw.space == 0 {
+				mw.space = ' '
+			}
+			continue
+		}
+		if mw.space != 0 {
+			mw.out, mw.space = append(mw.out, mw.space), 0
+		}
+		mw.out = append(mw.out, c)
+		switch {
+		case c == '<':
+			mw.tag, mw.reading = mw.tag[:0], true
+		case mw.reading && (c == '/' && len(mw.tag) == 0 || 'a' <= c|0x20 && c|0x20 <= 'z'):
+			mw.tag = append(mw.tag, c|0x20)
+		case c == '>' || c == '/':
+			mw.endTag()
+		default:
+			mw.reading = false
+		}
+	}
+	_, err := mw.w.Write(mw.out)
+	mw.out = mw.out[:0]
+	return len(p), err
+}
+
+func (mw *minifyWriter) Close() error {
+	if mw.space == 0 {
+		return nil
+	}
+	_, err := mw.w.Write([]byte{mw.space})
+	return err
+}
+
+func (mw *minifyWriter) endTag() {
+	if !mw.reading {
+		return
+	}
+	mw.reading = false
+	switch name := string(mw.tag); {
+	case mw.raw == "" && (name == "pre" || name == "textarea" || name == "script" || name == "style"):
+		mw.raw = name
+	case mw.raw != "" && name == "/"+mw.raw:
+		mw.raw = ""
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,260 @@
+package gotemp_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestTrimActions(t *testing.T) {
+	files := map[string]string{
+		"partials/list.html": "<ul>\n  {{ range . }}\n  <li>{{ . }}</li>\n  {{ end }}\n</ul>\n",
+	}
+	dir := writeTemplates(t, files)
+
+	plain, err := gotemp.New(dir, gotemp.WithOptionalPages(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	trimmed, err := gotemp.New(dir, gotemp.WithOptionalPages(true), gotemp.WithTrimActions(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	items := []string{"a", "b"}
+	var before, after bytes.Buffer
+	if err := plain.RenderPartial(&before, "list.html", items); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := trimmed.RenderPartial(&after, "list.html", items); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !bytes.Contains(before.Bytes(), []byte("\n  \n")) {
+		t.Fatalf("expected blank lines without trimming, got %q", before.String())
+	}
+	expected := "<ul>\n  <li>a</li>\n  <li>b</li>\n</ul>\n"
+	if after.String() != expected {
+		t.Errorf("expected %q, got %q", expected, after.String())
+	}
+}
+
+func TestTrimActionsPage(t *testing.T) {
+	g, err := gotemp.New("examples", gotemp.WithTrimActions(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, line := range bytes.Split(buf.Bytes(), []byte("\n")) {
+		if len(line) > 0 && len(bytes.TrimSpace(line)) == 0 {
+			t.Errorf("expected no whitespace-only lines, got %q", buf.String())
+			break
+		}
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("<h1>Homepage</h1>")) {
+		t.Error("expected page content in trimmed output")
+	}
+}
+
+func TestMaxOutputBytes(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/report/runaway.html": `{{ define "content" }}{{ range . }}<p>row {{ . }}</p>{{ end }}{{ end }}`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithMaxOutputBytes(1024))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "report/runaway.html", make([]int, 5)); err != nil {
+		t.Fatalf("expected small output to render, got %v", err)
+	}
+
+	buf.Reset()
+	err = g.RenderPage(&buf, "app_layout", "report/runaway.html", make([]int, 100000))
+	if !errors.Is(err, gotemp.ErrOutputTooLarge) {
+		t.Fatalf("expected ErrOutputTooLarge, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "report/runaway.html") {
+		t.Errorf("expected error to name the page, got %v", err)
+	}
+	if buf.Len() > 1024 {
+		t.Errorf("expected output to stop at the limit, wrote %d bytes", buf.Len())
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n *int
+}
+
+func (cw countingWriter) Write(p []byte) (int, error) {
+	*cw.n += len(p)
+	return cw.w.Write(p)
+}
+
+func TestOutputMiddleware(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": "{{ define \"content\" }}\n  <h1>  {{ . }}  </h1>\n\n  <pre>a\n  b</pre>\n  <script>let s = \"x  y\"</script>\n{{ end }}",
+	})
+	minified, err := gotemp.New(dir, gotemp.WithOutputMiddleware(gotemp.Minify))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := minified.RenderPage(&buf, "app_layout", "home/index.html", "Ada"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "<html><body>\n<h1> Ada </h1>\n<pre>a\n  b</pre>\n<script>let s = \"x  y\"</script>\n</body></html>"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	var counted int
+	chained, err := gotemp.New(dir, gotemp.WithOutputMiddleware(gotemp.Minify, gotemp.Gzip, func(w io.Writer) io.Writer {
+		return countingWriter{w: w, n: &counted}
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var compressed bytes.Buffer
+	if err := chained.RenderPage(&compressed, "app_layout", "home/index.html", "Ada"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if counted != compressed.Len() {
+		t.Errorf("expected the last middleware to count the %d compressed bytes, counted %d", compressed.Len(), counted)
+	}
+	zr, err := gzip.NewReader(&compressed)
+	if err != nil {
+		t.Fatalf("expected gzip output, got %v", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("expected complete gzip stream, got %v", err)
+	}
+	if string(out) != want {
+		t.Errorf("expected minified page after decompression, got %q", out)
+	}
+}
+
+func TestGzipHandler(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"partials/box.html":     `{{ define "box" }}<b>{{ . }}</b>{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ partial "box.html" "hi" }}{{ end }}`,
+	}), gotemp.WithOutputMiddleware(gotemp.Gzip))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	html, err := g.RenderPartialHTML("box.html", "hi")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if html != "<b>hi</b>" {
+		t.Errorf("expected partials to skip the output middleware, got %q", html)
+	}
+
+	want := "<html><body><b>hi</b></body></html>"
+	h := g.Handler("app_layout")
+	for _, accept := range []string{"gzip, br", "br", "gzip;q=0"} {
+		req := httptest.NewRequest(http.MethodGet, "/home/", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if vary := rec.Header().Values("Vary"); !slices.Contains(vary, "Accept-Encoding") {
+			t.Errorf("%s: expected Vary: Accept-Encoding, got %v", accept, vary)
+		}
+		body := rec.Body.Bytes()
+		if accept == "gzip, br" {
+			if rec.Header().Get("Content-Encoding") != "gzip" {
+				t.Errorf("%s: expected Content-Encoding gzip, got %q", accept, rec.Header().Get("Content-Encoding"))
+			}
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("%s: expected a gzip body, got %v", accept, err)
+			}
+			if body, err = io.ReadAll(zr); err != nil {
+				t.Fatalf("%s: expected a complete gzip stream, got %v", accept, err)
+			}
+		} else if rec.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s: expected no Content-Encoding, got %q", accept, rec.Header().Get("Content-Encoding"))
+		}
+		if string(body) != want {
+			t.Errorf("%s: expected %q, got %q", accept, want, body)
+		}
+	}
+}
+
+func TestBuildStamp(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layouts/app.html":      `{{ define "app_layout" }}<html><body>{{ block "content" . }}{{ end }}</BODY></html>{{ end }}`,
+		"layouts/bare.html":     `{{ define "bare_layout" }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ range . }}<p>{{ . }}</p>{{ end }}{{ end }}`,
+		"feeds/plain.txt":       `plain`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithBuildStamp("v1.2.3"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	stamp := regexp.MustCompile(`<!-- built: v1\.2\.3 at \d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ -->`)
+	data := []string{strings.Repeat("x", 5000), "y"}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	out := buf.String()
+	if n := len(stamp.FindAllString(out, -1)); n != 1 {
+		t.Fatalf("expected exactly one stamp, got %d in %q", n, out)
+	}
+	if !stamp.MatchString(out[:strings.Index(out, "</BODY>")]) || !strings.HasSuffix(out, "</BODY></html>") {
+		t.Errorf("expected stamp before </body>, got %q", out[len(out)-80:])
+	}
+
+	buf.Reset()
+	if err := g.RenderPage(&buf, "bare_layout", "home/index.html", []string{"a"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out := buf.String(); len(stamp.FindAllString(out, -1)) != 1 || !strings.HasPrefix(out, "<p>a</p><!-- built:") {
+		t.Errorf("expected stamp at the end of output without </body>, got %q", out)
+	}
+
+	buf.Reset()
+	if err := g.RenderBlock(&buf, "app_layout", "home/index.html", "content", []string{"a"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<p>a</p>" {
+		t.Errorf("expected fragments to stay unstamped, got %q", buf.String())
+	}
+	buf.Reset()
+	if err := g.RenderText(&buf, "feeds/plain.txt", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "plain" {
+		t.Errorf("expected text output to stay unstamped, got %q", buf.String())
+	}
+
+	plain, err := gotemp.New(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf.Reset()
+	if err := plain.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(buf.String(), "built:") {
+		t.Errorf("expected no stamp by default, got %q", buf.String())
+	}
+}
@@ -0,0 +1,60 @@
+package gotemp
+
+import (
+	"fmt"
+	"io"
+	"maps"
+)
+
+type Pagination struct {
+	Current    int
+	PageSize   int
+	TotalItems int
+	TotalPages int
+	HasPrev    bool
+	HasNext    bool
+	PrevPage   int
+	NextPage   int
+	Items      []any
+}
+
+func paginate(items []any, pageNum, pageSize int) Pagination {
+	totalPages := max((len(items)+pageSize-1)/pageSize, 1)
+	current := min(max(pageNum, 1), totalPages)
+	start := min((current-1)*pageSize, len(items))
+	end := min(start+pageSize, len(items))
+	p := Pagination{
+		Current:    current,
+		PageSize:   pageSize,
+		TotalItems: len(items),
+		TotalPages: totalPages,
+		HasPrev:    current > 1,
+		HasNext:    current < totalPages,
+		Items:      items[start:end:end],
+	}
+	if p.HasPrev {
+		p.PrevPage = current - 1
+	}
+	if p.HasNext {
+		p.NextPage = current + 1
+	}
+	return p
+}
+
+func (tc *Gotemp) RenderPaged(w io.Writer, layout, page string, items []any, pageNum, pageSize int, data any) error {
+	if pageSize <= 0 {
+		return fmt.Errorf("page %s: page size must be positive, got %d", page, pageSize)
+	}
+	pagination := paginate(items, pageNum, pageSize)
+	switch fields := data.(type) {
+	case nil:
+		data = map[string]any{"Pagination": pagination}
+	case map[string]any:
+		fields = maps.Clone(fields)
+		fields["Pagination"] = pagination
+		data = fields
+	default:
+		return fmt.Errorf("page %s: paged data must be a map[string]any, got %T", page, data)
+	}
+	return tc.RenderPage(w, layout, page, data)
+}
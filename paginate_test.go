@@ -0,0 +1,54 @@
+package gotemp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestRenderPaged(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/posts/index.html": `{{ define "content" }}{{ .Title }} {{ with .Pagination }}{{ .Current }}/{{ .TotalPages }}` +
+			` prev={{ .HasPrev }}:{{ .PrevPage }} next={{ .HasNext }}:{{ .NextPage }} [{{ range .Items }}{{ . }}{{ end }}]{{ end }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	items := []any{"a", "b", "c", "d", "e"}
+	tests := []struct {
+		name     string
+		items    []any
+		pageNum  int
+		pageSize int
+		want     string
+	}{
+		{"first page", items, 1, 2, "Posts 1/3 prev=false:0 next=true:2 [ab]"},
+		{"middle page", items, 2, 2, "Posts 2/3 prev=true:1 next=true:3 [cd]"},
+		{"last page", items, 3, 2, "Posts 3/3 prev=true:2 next=false:0 [e]"},
+		{"past the last page", items, 9, 2, "Posts 3/3 prev=true:2 next=false:0 [e]"},
+		{"before the first page", items, 0, 2, "Posts 1/3 prev=false:0 next=true:2 [ab]"},
+		{"single page", items, 1, 10, "Posts 1/1 prev=false:0 next=false:0 [abcde]"},
+		{"empty list", nil, 1, 10, "Posts 1/1 prev=false:0 next=false:0 []"},
+	}
+	for _, tt := range tests {
+		var buf strings.Builder
+		data := map[string]any{"Title": "Posts"}
+		if err := g.RenderPaged(&buf, "app_layout", "posts/index.html", tt.items, tt.pageNum, tt.pageSize, data); err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.name, err)
+		}
+		if want := "<html><body>" + tt.want + "</body></html>"; buf.String() != want {
+			t.Errorf("%s: expected %q, got %q", tt.name, want, buf.String())
+		}
+		if _, ok := data["Pagination"]; ok {
+			t.Errorf("%s: expected the caller's data to be left untouched", tt.name)
+		}
+	}
+
+	if err := g.RenderPaged(&strings.Builder{}, "app_layout", "posts/index.html", items, 1, 0, nil); err == nil {
+		t.Error("expected a zero page size to fail")
+	}
+	if err := g.RenderPaged(&strings.Builder{}, "app_layout", "posts/index.html", items, 1, 2, struct{}{}); err == nil {
+		t.Error("expected non-map data to fail")
+	}
+}
@@ -0,0 +1,26 @@
+package gotemp
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+type panicError struct {
+	value any
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrRenderPanic, e.value)
+}
+
+func (e *panicError) Unwrap() error {
+	return ErrRenderPanic
+}
+
+func (tc *Gotemp) recovered(r any) *panicError {
+	if err, ok := r.(*panicError); ok {
+		return err
+	}
+	tc.logger().Error("gotemp: recovered panic during render", "panic", r, "stack", string(debug.Stack()))
+	return &panicError{value: r}
+}
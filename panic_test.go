@@ -0,0 +1,59 @@
+package gotemp_test
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+type panicWriter struct{}
+
+func (panicWriter) Write([]byte) (int, error) {
+	panic("writer exploded")
+}
+
+func TestRenderPanics(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}{{ boom }}{{ end }}`,
+		"pages/home/slice.html": `{{ define "content" }}{{ explode "a" "b" }}{{ end }}`,
+		"pages/home/ok.html":    `{{ define "content" }}OK{{ end }}`,
+		"mail/welcome.txt":      `Welcome`,
+	}), gotemp.WithLogger(slog.New(slog.DiscardHandler)), gotemp.WithFuncs(template.FuncMap{
+		"boom":    func() string { panic("kaboom") },
+		"explode": func(parts ...string) (string, error) { panic(strings.Join(parts, ",")) },
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for page, want := range map[string]string{"home/index.html": "error calling boom: kaboom", "home/slice.html": "error calling explode: a,b"} {
+		err := g.RenderPage(io.Discard, "app_layout", page, nil)
+		if err == nil || errors.Is(err, gotemp.ErrRenderPanic) {
+			t.Fatalf("expected a template error from %s, got %v", page, err)
+		}
+		if !strings.HasPrefix(err.Error(), "page "+page+": ") || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error from %s to name the page and the panic, got %v", page, err)
+		}
+	}
+
+	err = g.RenderPage(panicWriter{}, "app_layout", "home/ok.html", nil)
+	if !errors.Is(err, gotemp.ErrRenderPanic) || !strings.Contains(err.Error(), "writer exploded") {
+		t.Errorf("expected a panicking writer to return ErrRenderPanic, got %v", err)
+	}
+	if err := g.RenderText(panicWriter{}, "mail/welcome.txt", nil); !errors.Is(err, gotemp.ErrRenderPanic) {
+		t.Errorf("expected ErrRenderPanic from RenderText, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 from the handler, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package gotemp
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"path"
+	"reflect"
+)
+
+func (tc *Gotemp) RenderPageParams(w io.Writer, layout string, params map[string]any, page string, data any) error {
+	data, err := tc.jsonData(data)
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	data, err = withDataKey(data, "Params", params)
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	return tc.RenderPage(w, layout, page, data)
+}
+
+func (tc *Gotemp) RenderPageVia(w io.Writer, layout, page string, raw any, transformers ...string) error {
+	data := raw
+	for _, name := range transformers {
+		transform, ok := tc.transformers[name]
+		if !ok {
+			return fmt.Errorf("page %s: unknown transformer %q", page, name)
+		}
+		var err error
+		if data, err = transform(data); err != nil {
+			return fmt.Errorf("page %s: transformer %s: %w", page, name, err)
+		}
+	}
+	return tc.RenderPage(w, layout, page, data)
+}
+
+func withDataKey(data any, key string, value any) (any, error) {
+	switch data := data.(type) {
+	case nil:
+		return map[string]any{key: value}, nil
+	case map[string]any:
+		merged := maps.Clone(data)
+		merged[key] = value
+		return merged, nil
+	default:
+		return nil, fmt.Errorf("cannot add .%s to data of type %T, use map[string]any or nil", key, data)
+	}
+}
+
+func withDefaults(data any, defaults map[string]any) any {
+	if len(defaults) == 0 {
+		return data
+	}
+	switch data := data.(type) {
+	case nil:
+		return defaults
+	case map[string]any:
+		var merged map[string]any
+		for key, value := range defaults {
+			if _, exists := data[key]; !exists {
+				if merged == nil {
+					merged = maps.Clone(data)
+				}
+				merged[key] = value
+			}
+		}
+		if merged == nil {
+			return data
+		}
+		return merged
+	default:
+		return data
+	}
+}
+
+func (tc *Gotemp) pageDefaults(pageEntry *page) map[string]any {
+	defaults := maps.Clone(tc.pageDataDefaults[pageEntry.meta.Page])
+	if defaults == nil {
+		defaults = make(map[string]any, 2)
+	}
+	if pageEntry.values != nil {
+		defaults["Meta"] = pageEntry.values
+	}
+	defaults["Directives"] = parseDirectives(pageEntry.meta.Fields["directives"])
+	if tc.currentPageField {
+		defaults["CurrentPage"] = pageEntry.meta.Page
+	}
+	return defaults
+}
+
+func (tc *Gotemp) renderData(pageEntry *page, data any) (any, error) {
+	data, err := tc.jsonData(data)
+	if err != nil {
+		return nil, err
+	}
+	return withDefaults(tc.trustFields(pageEntry.meta.Page, data), pageEntry.defaults), nil
+}
+
+func (tc *Gotemp) jsonData(data any) (any, error) {
+	if !tc.jsonFields {
+		return data, nil
+	}
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return data, nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("map %T through JSON: %w", data, err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("map %T through JSON: %w", data, err)
+	}
+	return fields, nil
+}
+
+func (tc *Gotemp) trustFields(page string, data any) any {
+	fields, ok := data.(map[string]any)
+	if !ok {
+		return data
+	}
+	var trusted map[string]any
+	for pattern, names := range tc.trustedFields {
+		if matched, _ := path.Match(pattern, page); !matched {
+			continue
+		}
+		for _, name := range names {
+			if value, ok := fields[name].(string); ok {
+				if trusted == nil {
+					trusted = maps.Clone(fields)
+				}
+				trusted[name] = template.HTML(value)
+			}
+		}
+	}
+	if trusted == nil {
+		return data
+	}
+	return trusted
+}
@@ -0,0 +1,301 @@
+package gotemp_test
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestRenderPageParams(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html":      `{{ define "app_layout" }}<div class="{{ with .Params }}{{ .width }}{{ else }}narrow{{ end }}">{{ block "content" . }}{{ end }}</div>{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}Hello {{ .Name }}{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := map[string]any{"Name": "Ada"}
+	for _, test := range []struct {
+		params map[string]any
+		want   string
+	}{
+		{map[string]any{"width": "wide"}, `<div class="wide">Hello Ada</div>`},
+		{map[string]any{"width": "full"}, `<div class="full">Hello Ada</div>`},
+		{nil, `<div class="narrow">Hello Ada</div>`},
+	} {
+		var buf strings.Builder
+		if err := g.RenderPageParams(&buf, "app_layout", test.params, "home/index.html", data); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("expected %q, got %q", test.want, buf.String())
+		}
+	}
+	if _, ok := data["Params"]; ok {
+		t.Error("expected the caller's data map to stay unchanged")
+	}
+
+	var buf strings.Builder
+	if err := g.RenderPageParams(&buf, "app_layout", nil, "home/index.html", struct{ Name string }{"Ada"}); err == nil {
+		t.Error("expected error for struct data")
+	}
+}
+
+func TestBaseData(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html":      `{{ define "app_layout" }}<title>{{ site.Name }}</title>{{ block "content" . }}{{ end }}{{ end }}`,
+		"partials/footer.html":  `{{ define "footer" }}{{ site.Email }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}<p>{{ site.Email }} {{ .Name }}</p>{{ end }}`,
+		"feeds/hello.txt":       `{{ site.Name }}`,
+	}), gotemp.WithBaseData(map[string]any{"Name": "Acme", "Email": "help@acme.test"}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	type post struct {
+		Name string
+		Site string
+	}
+	for _, test := range []struct {
+		data any
+		want string
+	}{
+		{nil, `<title>Acme</title><p>help@acme.test </p>`},
+		{map[string]any{"Name": "Ada"}, `<title>Acme</title><p>help@acme.test Ada</p>`},
+		{post{Name: "Ada", Site: "own"}, `<title>Acme</title><p>help@acme.test Ada</p>`},
+		{&post{Name: "Bob"}, `<title>Acme</title><p>help@acme.test Bob</p>`},
+	} {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", test.data); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("%#v: expected %q, got %q", test.data, test.want, buf.String())
+		}
+	}
+
+	var buf strings.Builder
+	if err := g.RenderPartial(&buf, "footer", post{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.RenderText(&buf, "feeds/hello.txt", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "help@acme.testAcme" {
+		t.Errorf("expected base data in partials and text templates, got %q", buf.String())
+	}
+
+	data := map[string]any{"Name": "Ada"}
+	if err := g.RenderPage(io.Discard, "app_layout", "home/index.html", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(data) != 1 {
+		t.Errorf("expected the caller's data to be left alone, got %v", data)
+	}
+	if _, err := gotemp.New(writeTemplates(t, map[string]string{}), gotemp.WithBaseData(map[string]any{}), gotemp.WithFuncs(template.FuncMap{"site": strings.ToUpper})); err == nil {
+		t.Error("expected site to be reserved with WithBaseData")
+	}
+}
+
+func TestTrustedFields(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/blog/post.html":  `{{ define "content" }}{{ .Body }}|{{ .Title }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ .Body }}{{ end }}`,
+	}), gotemp.WithTrustedFields("blog/*.html", "Body"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data := map[string]any{"Body": "<p>Hello</p>", "Title": "<b>Hi</b>"}
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "blog/post.html", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "<html><body><p>Hello</p>|&lt;b&gt;Hi&lt;/b&gt;</body></html>"; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+	if _, ok := data["Body"].(string); !ok {
+		t.Error("expected the caller's data map to stay unchanged")
+	}
+
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "<html><body>&lt;p&gt;Hello&lt;/p&gt;</body></html>"; buf.String() != want {
+		t.Errorf("expected fields of other pages to stay escaped, got %q", buf.String())
+	}
+}
+
+func TestJSONFieldMapping(t *testing.T) {
+	type author struct {
+		DisplayName string `json:"display_name"`
+	}
+	type post struct {
+		PostTitle string  `json:"post_title"`
+		Author    *author `json:"author"`
+		Secret    string  `json:"-"`
+	}
+	files := map[string]string{
+		"pages/blog/post.html": `{{ define "content" }}{{ .post_title }} by {{ .author.display_name }}{{ .Secret }}{{ with .Meta }}{{ .title }}{{ end }}{{ end }}`,
+		"pages/blog/go.html":   `{{ define "content" }}{{ .PostTitle }}{{ end }}`,
+	}
+	data := &post{PostTitle: "Hello", Author: &author{DisplayName: "Ada"}, Secret: "hidden"}
+
+	g, err := gotemp.New(writeTemplates(t, files), gotemp.WithJSONFieldMapping(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "blog/post.html", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<html><body>Hello by Ada</body></html>" {
+		t.Errorf("expected fields by JSON name, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.RenderPageParams(&buf, "app_layout", map[string]any{"id": "1"}, "blog/post.html", *data); err != nil {
+		t.Errorf("expected mapped struct data to accept params, got %v", err)
+	}
+
+	g, err = gotemp.New(writeTemplates(t, files))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "blog/go.html", data); err != nil || buf.String() != "<html><body>Hello</body></html>" {
+		t.Errorf("expected Go field names by default, got %q, %v", buf.String(), err)
+	}
+}
+
+func TestCurrentPageField(t *testing.T) {
+	files := map[string]string{
+		"partials/_nav.html": `{{ define "nav" }}<nav>{{ range $page := .Nav }}` +
+			`<a{{ if eq $.CurrentPage $page }} class="active"{{ end }}>{{ $page }}</a>{{ end }}</nav>{{ end }}`,
+		"layouts/app.html":      `{{ define "app_layout" }}{{ template "nav" . }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/blog/index.html": `{{ define "content" }}Blog{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+	}
+	nav := []string{"home/index.html", "blog/index.html"}
+
+	g, err := gotemp.New(writeTemplates(t, files), gotemp.WithCurrentPageField(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "blog/index.html", map[string]any{"Nav": nav}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := `<nav><a>home/index.html</a><a class="active">blog/index.html</a></nav>Blog`; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", map[string]any{"Nav": nav, "CurrentPage": "blog/index.html"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `<a class="active">blog/index.html</a>`) {
+		t.Errorf("expected caller data to win over the injected field, got %q", buf.String())
+	}
+
+	g, err = gotemp.New(writeTemplates(t, files))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "blog/index.html", map[string]any{"Nav": nav}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(buf.String(), "active") {
+		t.Errorf("expected no current page without the option, got %q", buf.String())
+	}
+}
+
+func TestPageDefaults(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}{{ .Title }}|{{ range .Features }}{{ . }},{{ end }}|{{ .Options.Color }}{{ .Options.Size }}{{ end }}`,
+		"pages/home/other.html": `{{ define "content" }}{{ .Title }}{{ end }}`,
+	}),
+		gotemp.WithPageDefaults("home/index.html", map[string]any{"Title": "Home", "Features": []string{"fast", "safe"}}),
+		gotemp.WithPageDefaults("home/index.html", map[string]any{"Options": map[string]any{"Color": "red", "Size": "L"}}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	render := func(page string, data any) string {
+		t.Helper()
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", page, data); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return buf.String()
+	}
+
+	if out := render("home/index.html", nil); out != "<html><body>Home|fast,safe,|redL</body></html>" {
+		t.Errorf("expected the defaults, got %q", out)
+	}
+	data := map[string]any{"Title": "Welcome", "Options": map[string]any{"Color": "blue"}}
+	if out := render("home/index.html", data); out != "<html><body>Welcome|fast,safe,|blue</body></html>" {
+		t.Errorf("expected caller keys to replace defaults whole, got %q", out)
+	}
+	if len(data) != 2 {
+		t.Errorf("expected the caller's map to be left unchanged, got %v", data)
+	}
+	if out := render("home/other.html", nil); out != "<html><body></body></html>" {
+		t.Errorf("expected defaults to apply to their page only, got %q", out)
+	}
+}
+
+func TestRenderPageVia(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html":       `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/orders/show.html": `{{ define "content" }}{{ .Name }}: {{ .Total }}{{ end }}`,
+	}),
+		gotemp.WithTransformer("order", func(raw any) (any, error) {
+			id, ok := raw.(int)
+			if !ok {
+				return nil, errors.New("expected an order id")
+			}
+			return map[string]any{"Name": fmt.Sprintf("Order %d", id), "Items": []int{3, 4}}, nil
+		}),
+		gotemp.WithTransformer("totals", func(data any) (any, error) {
+			view := data.(map[string]any)
+			total := 0
+			for _, item := range view["Items"].([]int) {
+				total += item
+			}
+			view["Total"] = total
+			return view, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf strings.Builder
+	if err := g.RenderPageVia(&buf, "app_layout", "orders/show.html", 7, "order", "totals"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "Order 7: 7" {
+		t.Errorf("expected transformed data, got %q", buf.String())
+	}
+
+	buf.Reset()
+	err = g.RenderPageVia(&buf, "app_layout", "orders/show.html", "seven", "order", "totals")
+	if err == nil || !strings.Contains(err.Error(), "transformer order: expected an order id") {
+		t.Errorf("expected transformer error to abort the render, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing written, got %q", buf.String())
+	}
+	if err := g.RenderPageVia(&buf, "app_layout", "orders/show.html", 7, "missing"); err == nil {
+		t.Error("expected unknown transformer to fail")
+	}
+}
@@ -0,0 +1,164 @@
+package gotemp
+
+import (
+	"html/template"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template/parse"
+)
+
+func parseTrees(name, text string) (map[string]*parse.Tree, error) {
+	treeSet := make(map[string]*parse.Tree)
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(text, "", "", treeSet); err != nil {
+		return nil, err
+	}
+	return treeSet, nil
+}
+
+func implicitContent(name, body string) string {
+	treeSet, err := parseTrees(name, body)
+	if err != nil || len(treeSet) != 1 || parse.IsEmptyTree(treeSet[name].Root) {
+		return body
+	}
+	content := strings.TrimSpace(body)
+	start := strings.Index(body, content)
+	return body[:start] + `{{ define "content" }}` + content + `{{ end }}` + body[start+len(content):]
+}
+
+func partialEntrypoint(name string, treeSet map[string]*parse.Tree) string {
+	if main := treeSet[name]; main != nil && !parse.IsEmptyTree(main.Root) {
+		return name
+	}
+	var defined []string
+	for treeName := range treeSet {
+		if treeName != name {
+			defined = append(defined, treeName)
+		}
+	}
+	if len(defined) == 1 {
+		return defined[0]
+	}
+	return name
+}
+
+func appendToActions(t *template.Template, funcName string) {
+	appendCommand(t, funcName, nil)
+}
+
+func appendCommand(t *template.Template, funcName string, args func(*parse.Tree, *parse.ActionNode) ([]parse.Node, bool)) {
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil && tmpl.Tree.Root != nil {
+			appendToActionsIn(tmpl.Tree, tmpl.Tree.Root, funcName, args)
+		}
+	}
+}
+
+func appendToActionsIn(tree *parse.Tree, node parse.Node, funcName string, args func(*parse.Tree, *parse.ActionNode) ([]parse.Node, bool)) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			appendToActionsIn(tree, child, funcName, args)
+		}
+	case *parse.IfNode:
+		appendToActionsIn(tree, node.List, funcName, args)
+		appendToActionsIn(tree, node.ElseList, funcName, args)
+	case *parse.RangeNode:
+		appendToActionsIn(tree, node.List, funcName, args)
+		appendToActionsIn(tree, node.ElseList, funcName, args)
+	case *parse.WithNode:
+		appendToActionsIn(tree, node.List, funcName, args)
+		appendToActionsIn(tree, node.ElseList, funcName, args)
+	case *parse.ActionNode:
+		pipe := node.Pipe
+		if len(pipe.Decl) > 0 || len(pipe.Cmds) == 0 {
+			return
+		}
+		for _, cmd := range pipe.Cmds {
+			if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok && ident.Ident == funcName {
+				return
+			}
+		}
+		last := pipe.Cmds[len(pipe.Cmds)-1]
+		if ident, ok := last.Args[0].(*parse.IdentifierNode); ok {
+			switch ident.Ident {
+			case "html", "urlquery", "js":
+				return
+			}
+		}
+		cmd := &parse.CommandNode{NodeType: parse.NodeCommand, Pos: pipe.Pos}
+		cmd.Args = []parse.Node{parse.NewIdentifier(funcName).SetTree(tree).SetPos(pipe.Pos)}
+		if args != nil {
+			extra, ok := args(tree, node)
+			if !ok {
+				return
+			}
+			cmd.Args = append(cmd.Args, extra...)
+		}
+		pipe.Cmds = append(pipe.Cmds, cmd)
+	}
+}
+
+func pipelineStartsWith(node *parse.ActionNode, names ...string) bool {
+	ident, ok := node.Pipe.Cmds[0].Args[0].(*parse.IdentifierNode)
+	return ok && slices.Contains(names, ident.Ident)
+}
+
+func usesIdentifier(t *template.Template, names ...string) bool {
+	found := false
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+		walkNodes(tmpl.Tree.Root, func(node parse.Node) {
+			if ident, ok := node.(*parse.IdentifierNode); ok && slices.Contains(names, ident.Ident) {
+				found = true
+			}
+		})
+	}
+	return found
+}
+
+func walkNodes(node parse.Node, visit func(parse.Node)) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+	visit(node)
+	switch node := node.(type) {
+	case *parse.ListNode:
+		for _, child := range node.Nodes {
+			walkNodes(child, visit)
+		}
+	case *parse.ActionNode:
+		walkNodes(node.Pipe, visit)
+	case *parse.PipeNode:
+		for _, cmd := range node.Cmds {
+			walkNodes(cmd, visit)
+		}
+	case *parse.CommandNode:
+		for _, arg := range node.Args {
+			walkNodes(arg, visit)
+		}
+	case *parse.ChainNode:
+		walkNodes(node.Node, visit)
+	case *parse.IfNode:
+		walkNodes(node.Pipe, visit)
+		walkNodes(node.List, visit)
+		walkNodes(node.ElseList, visit)
+	case *parse.RangeNode:
+		walkNodes(node.Pipe, visit)
+		walkNodes(node.List, visit)
+		walkNodes(node.ElseList, visit)
+	case *parse.WithNode:
+		walkNodes(node.Pipe, visit)
+		walkNodes(node.List, visit)
+		walkNodes(node.ElseList, visit)
+	case *parse.TemplateNode:
+		walkNodes(node.Pipe, visit)
+	}
+}
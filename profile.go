@@ -0,0 +1,43 @@
+package gotemp
+
+import (
+	"html/template"
+	"maps"
+	"time"
+)
+
+type includeFunc func(name string, data any) (template.HTML, error)
+
+func (tc *Gotemp) profiled(include includeFunc) includeFunc {
+	if !tc.profiling {
+		return include
+	}
+	return func(name string, data any) (template.HTML, error) {
+		start := time.Now()
+		html, err := include(name, data)
+		elapsed := time.Since(start)
+		tc.profileMu.Lock()
+		if tc.profile == nil {
+			tc.profile = make(map[string]time.Duration)
+		}
+		tc.profile[name] += elapsed
+		tc.profileMu.Unlock()
+		return html, err
+	}
+}
+
+func (tc *Gotemp) Profile() map[string]time.Duration {
+	tc.profileMu.Lock()
+	defer tc.profileMu.Unlock()
+	profile := maps.Clone(tc.profile)
+	if profile == nil {
+		profile = make(map[string]time.Duration)
+	}
+	return profile
+}
+
+func (tc *Gotemp) ResetProfile() {
+	tc.profileMu.Lock()
+	defer tc.profileMu.Unlock()
+	clear(tc.profile)
+}
@@ -0,0 +1,63 @@
+package gotemp_test
+
+import (
+	"html/template"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestProfiling(t *testing.T) {
+	files := map[string]string{
+		"partials/slow.html":       `{{ nap }}slow`,
+		"partials/fast.html":       `fast`,
+		"pages/widgets/clock.html": `{{ define "content" }}{{ partial "slow.html" . }}{{ end }}`,
+		"pages/home/index.html":    `{{ define "content" }}{{ partial "fast.html" . }}{{ renderPage "widgets/clock.html" . }}{{ end }}`,
+	}
+	nap := gotemp.WithFuncs(template.FuncMap{"nap": func() string {
+		time.Sleep(10 * time.Millisecond)
+		return ""
+	}})
+
+	g, err := gotemp.New(writeTemplates(t, files), nap, gotemp.WithProfiling(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for range 2 {
+		if err := g.RenderPage(io.Discard, "app_layout", "home/index.html", nil); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	profile := g.Profile()
+	if profile["slow.html"] < 20*time.Millisecond {
+		t.Errorf("expected both renders of slow.html to be recorded, got %v", profile)
+	}
+	if profile["widgets/clock.html"] < profile["slow.html"] {
+		t.Errorf("expected the embedded page to include its partial's time, got %v", profile)
+	}
+	if _, ok := profile["fast.html"]; !ok || profile["fast.html"] >= profile["slow.html"] {
+		t.Errorf("expected fast.html to be recorded as the faster include, got %v", profile)
+	}
+
+	profile["slow.html"] = 0
+	if g.Profile()["slow.html"] == 0 {
+		t.Error("expected Profile to return a copy")
+	}
+	g.ResetProfile()
+	if len(g.Profile()) != 0 {
+		t.Errorf("expected ResetProfile to empty the profile, got %v", g.Profile())
+	}
+
+	g, err = gotemp.New(writeTemplates(t, files), nap)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.RenderPage(io.Discard, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(g.Profile()) != 0 {
+		t.Errorf("expected no profile without WithProfiling, got %v", g.Profile())
+	}
+}
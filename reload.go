@@ -0,0 +1,254 @@
+package gotemp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+type ReloadStrategy int
+
+const (
+	Manual ReloadStrategy = iota
+	Checksum
+)
+
+type treeSignature struct {
+	files  int
+	size   int64
+	newest time.Time
+}
+
+func (tc *Gotemp) signature() (treeSignature, error) {
+	var sig treeSignature
+	err := tc.walkSources(func(name string, entry fs.DirEntry) error {
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		sig.files++
+		sig.size += info.Size()
+		if info.ModTime().After(sig.newest) {
+			sig.newest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return treeSignature{}, err
+	}
+	return sig, nil
+}
+
+func (tc *Gotemp) sourceHash() (string, error) {
+	hash := sha256.New()
+	err := tc.walkSources(func(name string, entry fs.DirEntry) error {
+		content, err := fs.ReadFile(tc.fsys, name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00", name, len(content))
+		hash.Write(content)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (tc *Gotemp) walkSources(visit func(name string, entry fs.DirEntry) error) error {
+	roots := []string{"root.html", "partials", "layouts", "pages", "feeds"}
+	alwaysFiles, err := tc.alwaysFiles()
+	if err != nil {
+		return err
+	}
+	extraFiles, err := tc.extraDefineFiles()
+	if err != nil {
+		return err
+	}
+	for _, file := range append(alwaysFiles, extraFiles...) {
+		if !isTemplatePath(file) && !strings.HasPrefix(file, "pages/") {
+			roots = append(roots, file)
+		}
+	}
+	for _, root := range roots {
+		err := fs.WalkDir(tc.fsys, root, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			return visit(name, entry)
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tc *Gotemp) reloadIfChanged() error {
+	if tc.closed.Load() {
+		return ErrClosed
+	}
+	if tc.reloadStrategy != Checksum {
+		return nil
+	}
+	return tc.reloadChanged()
+}
+
+func (tc *Gotemp) reloadChanged() error {
+	sig, err := tc.signature()
+	if err != nil {
+		return err
+	}
+	if last := tc.loadedSignature.Load(); last != nil && *last == sig {
+		return nil
+	}
+
+	tc.reloadMu.Lock()
+	defer tc.reloadMu.Unlock()
+	if last := tc.loadedSignature.Load(); last != nil && *last == sig {
+		return nil
+	}
+	return tc.loadPages()
+}
+
+func (tc *Gotemp) startPolling() {
+	stop, done := make(chan struct{}), make(chan struct{})
+	tc.stopPoll, tc.pollDone = stop, done
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(tc.pollInterval)
+		defer ticker.Stop()
+		var failed string
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := tc.reloadChanged(); err == nil {
+					failed = ""
+				} else if err.Error() != failed && !errors.Is(err, ErrClosed) {
+					failed = err.Error()
+					tc.logger().Error("gotemp: poll reload failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (tc *Gotemp) Close() error {
+	tc.closeOnce.Do(func() {
+		tc.closed.Store(true)
+		if tc.stopPoll != nil {
+			close(tc.stopPoll)
+			<-tc.pollDone
+		}
+		tc.reloadMu.Lock()
+		defer tc.reloadMu.Unlock()
+		tc.rawCache.Clear()
+		tc.textCache.Clear()
+		if tc.renderCache != nil {
+			tc.renderCache.clear()
+		}
+		if tc.partialCache != nil {
+			tc.partialCache.clear()
+		}
+	})
+	var errs []error
+	for _, engine := range tc.themes {
+		errs = append(errs, engine.Close())
+	}
+	tc.namespacesMu.RLock()
+	defer tc.namespacesMu.RUnlock()
+	for _, engine := range tc.namespaces {
+		errs = append(errs, engine.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (tc *Gotemp) UpdateTemplate(name, content string) error {
+	return tc.editTemplate("update", path.Clean(strings.TrimPrefix(name, "/")), content, false)
+}
+
+func (tc *Gotemp) AddPage(key, content string) error {
+	name := templateFile("pages", key)
+	if strings.Count(name, "/") != 2 {
+		return fmt.Errorf("add %s: pages must be in a subdirectory, like dir/page.html", name)
+	}
+	return tc.editTemplate("add", name, content, true)
+}
+
+func (tc *Gotemp) AddPartial(name, content string) error {
+	return tc.editTemplate("add", templateFile("partials", name), content, true)
+}
+
+func (tc *Gotemp) AddLayout(name, content string) error {
+	name = templateFile("layouts", name)
+	if strings.Count(name, "/") != 1 {
+		return fmt.Errorf("add %s: layouts must be at the top of layouts/", name)
+	}
+	return tc.editTemplate("add", name, content, true)
+}
+
+func templateFile(dir, name string) string {
+	if path.Ext(name) == "" {
+		name += ".html"
+	}
+	return path.Join(dir, path.Clean("/"+name))
+}
+
+func (tc *Gotemp) editTemplate(op, name, content string, create bool) error {
+	if !isTemplatePath(name) {
+		return fmt.Errorf("%s %s: not a root, partial, layout or page template", op, name)
+	}
+	if _, err := parseTrees(name, content); err != nil {
+		return fmt.Errorf("%s %s: %w", op, name, err)
+	}
+
+	tc.reloadMu.Lock()
+	defer tc.reloadMu.Unlock()
+	if create {
+		if _, err := fs.Stat(tc.fsys, name); err == nil {
+			return fmt.Errorf("%s %s: %w", op, name, fs.ErrExist)
+		}
+	}
+	previous, existed := tc.edits.set(name, content)
+	if err := tc.loadPages(); err != nil {
+		tc.edits.restore(name, previous, existed)
+		return fmt.Errorf("%s %s: %w", op, name, err)
+	}
+	if err := tc.forEngines((*Gotemp).Reload); err != nil {
+		tc.edits.restore(name, previous, existed)
+		tc.loadPages()
+		tc.forEngines((*Gotemp).Reload)
+		return fmt.Errorf("%s %s: %w", op, name, err)
+	}
+	return nil
+}
+
+func isTemplatePath(name string) bool {
+	if !fs.ValidPath(name) || path.Ext(name) != ".html" {
+		return false
+	}
+	if name == "root.html" {
+		return true
+	}
+	dir, _, nested := strings.Cut(name, "/")
+	return nested && (dir == "partials" || dir == "layouts" || dir == "pages")
+}
+
+func (tc *Gotemp) Ready() error {
+	set := tc.set.Load()
+	if set == nil || len(set.pages) == 0 {
+		return fmt.Errorf("%w: no pages loaded", ErrNotReady)
+	}
+	if tc.defaultLayout != "" && set.layouts.Lookup(tc.defaultLayout) == nil {
+		return fmt.Errorf("%w: default layout %s not defined", ErrNotReady, tc.defaultLayout)
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package gotemp_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestReloadChecksum(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"partials/_footer.html": `{{ define "footer" }}<footer>v1</footer>{{ end }}`,
+		"layouts/app.html":      `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ template "footer" . }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithReloadStrategy(gotemp.Checksum))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	render := func() (string, error) {
+		var buf strings.Builder
+		err := g.RenderPage(&buf, "app_layout", "home/index.html", nil)
+		return buf.String(), err
+	}
+	edit := func(name, content string) {
+		t.Helper()
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if out, _ := render(); out != "Home<footer>v1</footer>" {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	edit("partials/_footer.html", `{{ define "footer" }}<footer>v2</footer>{{ end }}`)
+	if out, err := render(); err != nil || out != "Home<footer>v2</footer>" {
+		t.Errorf("expected edited partial to be picked up, got %q, %v", out, err)
+	}
+
+	edit("pages/home/index.html", `{{ define "content" }}{{ if }}{{ end }}`)
+	if _, err := render(); err == nil {
+		t.Error("expected reload error for a broken template")
+	}
+	edit("pages/home/index.html", `{{ define "content" }}Fixed{{ end }}`)
+	if out, err := render(); err != nil || out != "Fixed<footer>v2</footer>" {
+		t.Errorf("expected fixed template to be picked up, got %q, %v", out, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "pages/home/about.html"), []byte(`{{ define "content" }}About{{ end }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "home/about.html", nil); err != nil {
+		t.Errorf("expected new page to be picked up, got %v", err)
+	}
+}
+
+func TestReloadManual(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}v1{{ end }}`,
+	})
+	g, err := gotemp.New(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pages/home/index.html"), []byte(`{{ define "content" }}version 2{{ end }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "v1") {
+		t.Errorf("expected no automatic reload by default, got %q", buf.String())
+	}
+}
+
+func BenchmarkReloadChecksum(b *testing.B) {
+	for _, bench := range []struct {
+		name     string
+		strategy gotemp.ReloadStrategy
+		reload   bool
+	}{
+		{"manual", gotemp.Manual, false},
+		{"checksum", gotemp.Checksum, false},
+		{"reload every render", gotemp.Manual, true},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			g, err := gotemp.New("examples", gotemp.WithReloadStrategy(bench.strategy))
+			if err != nil {
+				b.Fatalf("expected no error, got %v", err)
+			}
+			var buf strings.Builder
+			b.ReportAllocs()
+			for b.Loop() {
+				buf.Reset()
+				if bench.reload {
+					if err := g.Reload(); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateTemplate(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"partials/_footer.html": `{{ define "footer" }}<footer>disk</footer>{{ end }}`,
+		"layouts/app.html":      `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ template "footer" . }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+		"pages/home/about.html": `{{ define "content" }}About{{ end }}`,
+	})
+	g, err := gotemp.New(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	render := func(page string) string {
+		t.Helper()
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", page, nil); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return buf.String()
+	}
+
+	if err := g.UpdateTemplate("partials/_footer.html", `{{ define "footer" }}<footer>edited</footer>{{ end }}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, page := range []string{"home/index.html", "home/about.html"} {
+		if out := render(page); !strings.HasSuffix(out, "<footer>edited</footer>") {
+			t.Errorf("expected %s to use the edited partial, got %q", page, out)
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "partials/_footer.html"))
+	if err != nil || !strings.Contains(string(content), "disk") {
+		t.Errorf("expected the file on disk to be untouched, got %q", content)
+	}
+
+	if err := g.UpdateTemplate("partials/_footer.html", `{{ define "footer" }}{{ if }}{{ end }}`); err == nil {
+		t.Error("expected syntax error")
+	}
+	if err := g.UpdateTemplate("partials/_footer.html", `{{ define "footer" }}{{ nosuchfunc }}{{ end }}`); err == nil {
+		t.Error("expected load error for an undefined function")
+	}
+	if out := render("home/index.html"); out != "Home<footer>edited</footer>" {
+		t.Errorf("expected failed updates to leave the live set untouched, got %q", out)
+	}
+
+	if err := g.UpdateTemplate("pages/home/new.html", `{{ define "content" }}New{{ end }}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out := render("home/new.html"); out != "New<footer>edited</footer>" {
+		t.Errorf("expected the added page to render, got %q", out)
+	}
+	if err := g.UpdateTemplate("../secrets.html", "x"); err == nil {
+		t.Error("expected paths outside the template tree to be rejected")
+	}
+	if err := g.UpdateTemplate("static/app.css", "x"); err == nil {
+		t.Error("expected non-template paths to be rejected")
+	}
+}
+
+func TestReady(t *testing.T) {
+	g, err := gotemp.New("examples", gotemp.WithDefaultLayout("app_layout"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.Ready(); err != nil {
+		t.Errorf("expected ready, got %v", err)
+	}
+
+	g, err = gotemp.New("examples", gotemp.WithDefaultLayout("missing_layout"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.Ready(); !errors.Is(err, gotemp.ErrNotReady) || !strings.Contains(err.Error(), "missing_layout") {
+		t.Errorf("expected ErrNotReady naming the layout, got %v", err)
+	}
+
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+	})
+	g, err = gotemp.New(dir, gotemp.WithOptionalPages(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "pages")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Reload(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.Ready(); !errors.Is(err, gotemp.ErrNotReady) {
+		t.Errorf("expected ErrNotReady after reloading an empty set, got %v", err)
+	}
+}
+
+func TestAddTemplates(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"partials/_footer.html": `{{ define "footer" }}<footer>Footer</footer>{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := g.AddPage("plugins/hello", `{{ define "content" }}Hello{{ template "footer" . }}{{ end }}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf strings.Builder
+	if err := g.RenderPage(&buf, "app_layout", "plugins/hello.html", nil); err != nil {
+		t.Fatalf("expected the added page to render, got %v", err)
+	}
+	if buf.String() != "<html><body>Hello<footer>Footer</footer></body></html>" {
+		t.Errorf("expected the added page to use existing partials, got %q", buf.String())
+	}
+
+	if err := g.AddPartial("badge.html", `<span class="badge">{{ . }}</span>`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.AddLayout("plugin.html", `{{ define "plugin_layout" }}<main>{{ block "content" . }}{{ end }}</main>{{ end }}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.AddPage("plugins/badge.html", `{{ define "content" }}{{ partial "badge.html" "New" }}{{ end }}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf.Reset()
+	if err := g.RenderPage(&buf, "plugin_layout", "plugins/badge.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != `<main><span class="badge">New</span></main>` {
+		t.Errorf("expected the added layout and partial, got %q", buf.String())
+	}
+
+	if err := g.AddPage("home/index.html", `{{ define "content" }}Replaced{{ end }}`); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected fs.ErrExist for an existing page, got %v", err)
+	}
+	if err := g.AddPartial("broken.html", `{{ if }}`); err == nil {
+		t.Error("expected a parse error")
+	}
+	if err := g.AddPage("toplevel.html", `{{ define "content" }}{{ end }}`); err == nil {
+		t.Error("expected an error for a page outside a subdirectory")
+	}
+	if err := g.AddLayout("app", `{{ define "other_layout" }}{{ end }}`); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected fs.ErrExist for an existing layout, got %v", err)
+	}
+	if pages := g.ListPages(); len(pages) != 3 {
+		t.Errorf("expected failed additions to leave the set unchanged, got %v", pages)
+	}
+}
+
+func TestPollReload(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}v1{{ end }}`,
+	})
+	before := runtime.NumGoroutine()
+	g, err := gotemp.New(dir, gotemp.WithPollReload(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	render := func() string {
+		var buf strings.Builder
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return buf.String()
+	}
+	edit := func(content string) {
+		file := filepath.Join(dir, "pages/home/index.html")
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	edit(`{{ define "content" }}v2{{ end }}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for render() != "<html><body>v2</body></html>" {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the poller to pick up the edit, got %q", render())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := g.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := g.Close(); err != nil {
+		t.Fatalf("expected a second Close to be a no-op, got %v", err)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("expected Close to stop the poller, %d goroutines before and %d after", before, after)
+	}
+}
+
+func TestClose(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}Home{{ end }}`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithPollReload(time.Millisecond), gotemp.WithRenderCache(8))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 50 {
+				var buf strings.Builder
+				err := g.RenderPage(&buf, "app_layout", "home/index.html", nil)
+				if err != nil && !errors.Is(err, gotemp.ErrClosed) {
+					t.Errorf("expected a render or ErrClosed, got %v", err)
+					return
+				}
+				if err == nil && buf.String() != "<html><body>Home</body></html>" {
+					t.Errorf("unexpected output %q", buf.String())
+					return
+				}
+			}
+		})
+	}
+	wg.Go(func() {
+		if err := g.Close(); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+	wg.Wait()
+
+	if err := g.Close(); err != nil {
+		t.Errorf("expected a second Close to be a no-op, got %v", err)
+	}
+	if err := g.RenderPage(io.Discard, "app_layout", "home/index.html", nil); !errors.Is(err, gotemp.ErrClosed) {
+		t.Errorf("expected ErrClosed from RenderPage, got %v", err)
+	}
+	if err := g.RenderPartial(io.Discard, "app_layout", nil); !errors.Is(err, gotemp.ErrClosed) {
+		t.Errorf("expected ErrClosed from RenderPartial, got %v", err)
+	}
+	if err := g.Reload(); !errors.Is(err, gotemp.ErrClosed) {
+		t.Errorf("expected ErrClosed from Reload, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	g.Handler("app_layout").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 after Close, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package gotemp
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const maxRemoteInclude = 1 << 20
+
+var errHostNotAllowed = errors.New("host is not in the remote include allowlist")
+
+type remoteIncludes struct {
+	client *http.Client
+	ttl    time.Duration
+	hosts  []string
+
+	cache *renderCache
+}
+
+func newRemoteIncludes(client *http.Client, ttl time.Duration, hosts []string) *remoteIncludes {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	r := &remoteIncludes{ttl: ttl, cache: newRenderCache(remoteCacheSize)}
+	for _, host := range hosts {
+		r.hosts = append(r.hosts, strings.ToLower(host))
+	}
+	guarded := *client
+	guarded.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := r.check(req.URL); err != nil {
+			return err
+		}
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	r.client = &guarded
+	return r
+}
+
+func (r *remoteIncludes) check(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if !slices.Contains(r.hosts, strings.ToLower(u.Host)) && !slices.Contains(r.hosts, strings.ToLower(u.Hostname())) {
+		return fmt.Errorf("%w: %s", errHostNotAllowed, u.Host)
+	}
+	return nil
+}
+
+func (r *remoteIncludes) include(rawURL string) (template.HTML, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("includeURL %s: %w", rawURL, err)
+	}
+	if err := r.check(u); err != nil {
+		return "", fmt.Errorf("includeURL %s: %w", rawURL, err)
+	}
+
+	r.cache.mu.Lock()
+	cached, ok := r.cache.get(renderKey(rawURL))
+	r.cache.mu.Unlock()
+	if ok {
+		return template.HTML(cached.String()), nil
+	}
+
+	html, err := r.fetch(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("includeURL %s: %w", rawURL, err)
+	}
+	if r.ttl > 0 {
+		output := &assetBuffer{}
+		output.WriteString(string(html))
+		r.cache.mu.Lock()
+		r.cache.add(renderKey(rawURL), output, r.ttl)
+		r.cache.mu.Unlock()
+	}
+	return html, nil
+}
+
+func (r *remoteIncludes) fetch(rawURL string) (template.HTML, error) {
+	resp, err := r.client.Get(rawURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteInclude+1))
+	if err != nil {
+		return "", err
+	}
+	if len(body) > maxRemoteInclude {
+		return "", fmt.Errorf("response is larger than %d bytes", maxRemoteInclude)
+	}
+	return template.HTML(body), nil
+}
@@ -0,0 +1,91 @@
+package gotemp_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestRemoteIncludes(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/fragment":
+			w.Write([]byte("<aside>remote</aside>"))
+		case "/redirect":
+			http.Redirect(w, r, "http://blocked.example/fragment", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	dir := writeTemplates(t, map[string]string{
+		"layouts/app.html":         `{{ define "app_layout" }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/home/index.html":    `{{ define "content" }}{{ includeURL .URL }}{{ end }}`,
+		"pages/home/disabled.html": `{{ define "content" }}{{ includeURL "x" }}{{ end }}`,
+	})
+	if _, err := gotemp.New(dir); err == nil {
+		t.Fatal("expected includeURL to be unavailable without WithRemoteIncludes")
+	}
+
+	g, err := gotemp.New(dir, gotemp.WithRemoteIncludes(&http.Client{Timeout: time.Second}, time.Minute, host))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	render := func(url string) (string, error) {
+		var buf bytes.Buffer
+		err := g.RenderPage(&buf, "app_layout", "home/index.html", map[string]any{"URL": url})
+		return buf.String(), err
+	}
+
+	for range 2 {
+		out, err := render(server.URL + "/fragment")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if out != "<aside>remote</aside>" {
+			t.Errorf("expected remote fragment unescaped, got %q", out)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected cached response to be reused, got %d requests", hits.Load())
+	}
+
+	for _, url := range []string{
+		strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/fragment",
+		"http://169.254.169.254/latest/meta-data",
+		"file:///etc/passwd",
+		server.URL + "/redirect",
+		server.URL + "/missing",
+	} {
+		if _, err := render(url); err == nil {
+			t.Errorf("expected %s to be rejected", url)
+		}
+	}
+	if hits.Load() != 3 {
+		t.Errorf("expected blocked hosts not to be fetched, got %d requests", hits.Load())
+	}
+
+	for i := range 1024 {
+		if _, err := render(fmt.Sprintf("%s/fragment?n=%d", server.URL, i)); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	hits.Store(0)
+	if _, err := render(server.URL + "/fragment"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected the least recently used URL to be evicted, got %d requests", hits.Load())
+	}
+}
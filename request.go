@@ -0,0 +1,150 @@
+package gotemp
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"sync"
+)
+
+var errNoRequest = errors.New("not rendering a request")
+
+func requestFuncs(r *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"request": func() (*http.Request, error) {
+			if r == nil {
+				return nil, fmt.Errorf("request: %w", errNoRequest)
+			}
+			return r, nil
+		},
+		"requestPath": func() (string, error) {
+			if r == nil {
+				return "", fmt.Errorf("requestPath: %w", errNoRequest)
+			}
+			return r.URL.Path, nil
+		},
+		"isActive": func(route string) (bool, error) {
+			if r == nil {
+				return false, fmt.Errorf("isActive: %w", errNoRequest)
+			}
+			return path.Clean("/"+route) == path.Clean("/"+r.URL.Path), nil
+		},
+	}
+}
+
+func (tc *Gotemp) flagFunc(r *http.Request) func(name string) bool {
+	var flags map[string]bool
+	if r != nil {
+		flags = tc.flags(r)
+	}
+	return func(name string) bool {
+		return flags[name]
+	}
+}
+
+func (tc *Gotemp) roleFunc(r *http.Request) func(roles ...string) bool {
+	var granted []string
+	if r != nil {
+		granted = tc.roles(r)
+	}
+	return func(roles ...string) bool {
+		return slices.ContainsFunc(roles, func(role string) bool {
+			return slices.Contains(granted, role)
+		})
+	}
+}
+
+func (tc *Gotemp) perRequest() bool {
+	return tc.requestHelpers || tc.flags != nil || tc.roles != nil
+}
+
+type pristineSet struct {
+	once     sync.Once
+	build    func() (*template.Template, map[string]*template.Template, error)
+	template *template.Template
+	scoped   map[string]*template.Template
+	err      error
+}
+
+func (p *page) clone(layout string) (*template.Template, error) {
+	set := p.pristine
+	set.once.Do(func() {
+		set.template, set.scoped, set.err = set.build()
+	})
+	if set.err != nil {
+		return nil, set.err
+	}
+	pristine := set.template
+	if scoped := set.scoped[layout]; scoped != nil {
+		pristine = scoped
+	}
+	t, err := clone(pristine)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone page template: %w", err)
+	}
+	return t, nil
+}
+
+func (tc *Gotemp) RenderPageRequest(w io.Writer, r *http.Request, layout, page string, data any) error {
+	return tc.renderRequest(w, r, layout, page, "", data)
+}
+
+func (tc *Gotemp) renderRequest(w io.Writer, r *http.Request, layout, page, block string, data any) error {
+	if engine := tc.themeEngine(r); engine != tc {
+		return engine.renderRequest(w, r, layout, page, block, data)
+	}
+	if !tc.perRequest() {
+		if block != "" {
+			return tc.RenderBlock(w, layout, page, block, data)
+		}
+		return tc.RenderPage(w, layout, page, data)
+	}
+
+	if err := tc.reloadIfChanged(); err != nil {
+		return err
+	}
+	set := tc.set.Load()
+	pageEntry := set.pages[page]
+	if pageEntry == nil {
+		return fmt.Errorf("%w: %s", ErrPageNotFound, page)
+	}
+	if err := pageEntry.ready(); err != nil {
+		return err
+	}
+	layout = tc.pageLayout(pageEntry, layout)
+	data, err := tc.renderData(pageEntry, data)
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	t, err := pageEntry.clone(layout)
+	if err != nil {
+		return err
+	}
+	funcs := template.FuncMap{}
+	if tc.requestHelpers {
+		funcs = requestFuncs(r)
+	}
+	if tc.flags != nil {
+		funcs["flag"] = tc.flagFunc(r)
+	}
+	if tc.roles != nil {
+		funcs["hasRole"] = tc.roleFunc(r)
+	}
+	tc.bindRender(t, set.partials, newRenderState(pageEntry.meta.Page)).Funcs(funcs)
+
+	name := pageEntry.entry(t, layout)
+	if block != "" {
+		if name = pageEntry.entry(t, block); t.Lookup(name) == nil {
+			return fmt.Errorf("%w: %s in page %s", ErrBlockNotFound, block, page)
+		}
+	}
+	err = tc.execute(w, t, name, data, block == "")
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	return err
+}
@@ -0,0 +1,164 @@
+package gotemp_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func requestHelpersFixture(t *testing.T) string {
+	return writeTemplates(t, map[string]string{
+		"partials/_nav.html": `{{ define "nav" }}<nav>` +
+			`<a href="/home/index"{{ if isActive "/home/index" }} class="active"{{ end }}>Home</a>` +
+			`<a href="/home/about"{{ if isActive "/home/about" }} class="active"{{ end }}>About</a>` +
+			`</nav>{{ end }}`,
+		"layouts/app.html":      `{{ define "app_layout" }}{{ template "nav" . }}{{ block "content" . }}{{ end }}{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}<main>{{ requestPath }}</main>{{ end }}`,
+		"pages/home/about.html": `{{ define "content" }}<main>About</main>{{ end }}`,
+	})
+}
+
+func TestRequestHelpers(t *testing.T) {
+	g, err := gotemp.New(requestHelpersFixture(t), gotemp.WithRequestHelpers(true))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	h := g.Handler("app_layout")
+
+	for _, route := range []string{"/home/index", "/home/about", "/home/index"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, route, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200 for %s, got %d: %s", route, rec.Code, rec.Body.String())
+		}
+		active := `<a href="` + route + `" class="active">`
+		if !strings.Contains(rec.Body.String(), active) {
+			t.Errorf("expected active link for %s, got %q", route, rec.Body.String())
+		}
+		if strings.Count(rec.Body.String(), `class="active"`) != 1 {
+			t.Errorf("expected exactly one active link for %s, got %q", route, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/home/index", nil))
+	if !strings.Contains(rec.Body.String(), "<main>/home/index</main>") {
+		t.Errorf("expected request path in page, got %q", rec.Body.String())
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/about.html", nil); err == nil {
+		t.Error("expected request helpers to fail outside a request")
+	}
+	buf.Reset()
+	req := httptest.NewRequest(http.MethodGet, "/home/about", nil)
+	if err := g.RenderPageRequest(&buf, req, "app_layout", "home/about.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `<a href="/home/about" class="active">`) {
+		t.Errorf("expected active link from RenderPageRequest, got %q", buf.String())
+	}
+}
+
+func TestRequestHelpersRequireOption(t *testing.T) {
+	if _, err := gotemp.New(requestHelpersFixture(t)); err == nil {
+		t.Error("expected request helpers to be undefined without WithRequestHelpers")
+	}
+}
+
+func TestFlagsProvider(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}{{ if flag "newCheckout" }}new{{ else }}old{{ end }}{{ end }}`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithFlagsProvider(func(r *http.Request) map[string]bool {
+		return map[string]bool{"newCheckout": r.URL.Query().Get("beta") == "1"}
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	h := g.Handler("app_layout")
+
+	for route, want := range map[string]string{"/home/?beta=1": "new", "/home/": "old"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, route, nil))
+		if body := rec.Body.String(); body != "<html><body>"+want+"</body></html>" {
+			t.Errorf("expected %q for %s, got %d %q", want, route, rec.Code, body)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<html><body>old</body></html>" {
+		t.Errorf("expected flags to be off outside a request, got %q", buf.String())
+	}
+
+	if _, err := gotemp.New(dir); err == nil {
+		t.Error("expected flag to be undefined without WithFlagsProvider")
+	}
+}
+
+func TestRoleProvider(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html": `{{ define "content" }}<p>dash</p>{{ if hasRole "admin" "owner" }}<p>settings</p>{{ end }}{{ end }}`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithRoleProvider(func(r *http.Request) []string {
+		return strings.Split(r.Header.Get("X-Roles"), ",")
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	h := g.Handler("app_layout")
+
+	for roles, want := range map[string]string{"editor,admin": "<p>dash</p><p>settings</p>", "owner": "<p>dash</p><p>settings</p>", "editor": "<p>dash</p>", "": "<p>dash</p>"} {
+		req := httptest.NewRequest(http.MethodGet, "/home/", nil)
+		req.Header.Set("X-Roles", roles)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if body := rec.Body.String(); body != "<html><body>"+want+"</body></html>" {
+			t.Errorf("expected %q for roles %q, got %d %q", want, roles, rec.Code, body)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != "<html><body><p>dash</p></body></html>" {
+		t.Errorf("expected no roles outside a request, got %q", buf.String())
+	}
+}
+
+func TestRoleProviderCachedPartial(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"partials/menu.html":    `{{ define "menu" }}<ul>{{ if hasRole "admin" }}<li>admin</li>{{ end }}</ul>{{ end }}`,
+		"pages/home/index.html": `{{ define "content" }}{{ cachedPartial "menu.html" . }}{{ end }}`,
+	}),
+		gotemp.WithPartialCache("menu.html", time.Minute),
+		gotemp.WithRoleProvider(func(r *http.Request) []string {
+			return strings.Split(r.Header.Get("X-Roles"), ",")
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	h := g.Handler("app_layout")
+	for _, tt := range []struct{ roles, want string }{
+		{"admin", "<ul><li>admin</li></ul>"},
+		{"viewer", "<ul></ul>"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/home/", nil)
+		req.Header.Set("X-Roles", tt.roles)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if body := rec.Body.String(); body != "<html><body>"+tt.want+"</body></html>" {
+			t.Errorf("expected %q for roles %q, got %q", tt.want, tt.roles, body)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package gotemp
+
+import (
+	"fmt"
+	"html/template"
+	"path"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+const namespaceSeparator = "#"
+
+func namespaced(namespace, name string) string {
+	return namespace + namespaceSeparator + name
+}
+
+func (p *page) visibleName(name string) (string, bool) {
+	if p.namespace == "" {
+		return name, true
+	}
+	if own, ok := strings.CutPrefix(name, p.namespace+namespaceSeparator); ok {
+		return own, true
+	}
+	return name, !strings.Contains(name, namespaceSeparator)
+}
+
+func (tc *Gotemp) compileShared(pages map[string]*page, layouts *template.Template, scopes []layoutScope, partialNames map[string]string) (*template.Template, error) {
+	keys := make([]string, 0, len(pages))
+	for key := range pages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		pageEntry := pages[key]
+		name := path.Join("pages", pageEntry.path)
+		content, err := tc.readTemplate(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse page template %s: %w", name, err)
+		}
+		pageEntry.source = content
+	}
+	return tc.linkShared(pages, keys, layouts, scopes, partialNames)
+}
+
+func (tc *Gotemp) linkShared(pages map[string]*page, keys []string, layouts *template.Template, scopes []layoutScope, partialNames map[string]string) (*template.Template, error) {
+	build := func() (*template.Template, map[string]*template.Template, error) {
+		return tc.parseShared(pages, keys, layouts, scopes, partialNames)
+	}
+	shared, scoped, err := build()
+	if err != nil {
+		return nil, err
+	}
+	embeds := usesIdentifier(shared, embedFunc)
+	for _, set := range scoped {
+		embeds = embeds || usesIdentifier(set, embedFunc)
+	}
+	pristine := &pristineSet{build: build}
+	for _, pageEntry := range pages {
+		pageEntry.template, pageEntry.scoped = shared, scoped
+		pageEntry.embeds, pageEntry.pristine = embeds, pristine
+	}
+	return shared, nil
+}
+
+func (tc *Gotemp) parseShared(pages map[string]*page, keys []string, layouts *template.Template, scopes []layoutScope, partialNames map[string]string) (*template.Template, map[string]*template.Template, error) {
+	shared, err := clone(layouts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to clone layout template: %w", err)
+	}
+	scopedSets := make([]*template.Template, len(scopes))
+	for i, scope := range scopes {
+		if scopedSets[i], err = clone(scope.template); err != nil {
+			return nil, nil, fmt.Errorf("failed to clone scoped layout template: %w", err)
+		}
+	}
+
+	for _, key := range keys {
+		pageEntry := pages[key]
+		name := path.Join("pages", pageEntry.path)
+		parsed, err := template.New(path.Base(name)).Funcs(tc.funcs()).Parse(pageEntry.source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse page template %s: %w", name, err)
+		}
+		if err := specialize(shared, pageEntry.namespace, parsed); err != nil {
+			return nil, nil, fmt.Errorf("failed to add page template %s: %w", name, err)
+		}
+		for _, set := range scopedSets {
+			if err := specialize(set, pageEntry.namespace, parsed); err != nil {
+				return nil, nil, fmt.Errorf("failed to add page template %s: %w", name, err)
+			}
+		}
+	}
+
+	tc.bind(shared, partialNames)
+	scoped := make(map[string]*template.Template)
+	for i, scope := range scopes {
+		tc.bind(scopedSets[i], partialNames)
+		for _, layoutName := range scope.layouts {
+			scoped[layoutName] = scopedSets[i]
+		}
+	}
+	return shared, scoped, nil
+}
+
+func specialize(set *template.Template, namespace string, parsed *template.Template) error {
+	trees := make(map[string]*parse.Tree)
+	for _, t := range parsed.Templates() {
+		if t.Tree == nil || (parse.IsEmptyTree(t.Tree.Root) && set.Lookup(t.Name()) != nil) {
+			continue
+		}
+		trees[t.Name()] = t.Tree
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, t := range set.Templates() {
+			name := t.Name()
+			if _, ok := trees[name]; ok || t.Tree == nil || strings.Contains(name, namespaceSeparator) {
+				continue
+			}
+			if invokesAny(t.Tree, trees) {
+				trees[name] = t.Tree
+				changed = true
+			}
+		}
+	}
+
+	for name, tree := range trees {
+		copied := tree.Copy()
+		copied.Name = namespaced(namespace, name)
+		walkNodes(copied.Root, func(node parse.Node) {
+			if invoke, ok := node.(*parse.TemplateNode); ok {
+				if _, ok := trees[invoke.Name]; ok {
+					invoke.Name = namespaced(namespace, invoke.Name)
+				}
+			}
+		})
+		if _, err := set.AddParseTree(copied.Name, copied); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func invokesAny(tree *parse.Tree, names map[string]*parse.Tree) bool {
+	found := false
+	walkNodes(tree.Root, func(node parse.Node) {
+		if invoke, ok := node.(*parse.TemplateNode); ok {
+			if _, ok := names[invoke.Name]; ok {
+				found = true
+			}
+		}
+	})
+	return found
+}
@@ -0,0 +1,85 @@
+package gotemp_test
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestSharedTemplates(t *testing.T) {
+	fixtures := []struct {
+		dir     string
+		layouts []string
+	}{
+		{"examples", []string{"app_layout", "auth_layout"}},
+		{"testdata/scoped", []string{"app_layout", "marketing_layout"}},
+		{writeTemplates(t, map[string]string{
+			"root.html":             `{{ define "__start" }}<html><body>{{ end }}{{ define "__end" }}</body></html>{{ end }}{{ define "main" }}<main>{{ template "content" . }}</main>{{ end }}`,
+			"layouts/app.html":      `{{ define "app_layout" }}{{ template "__start" . }}{{ template "main" . }}{{ block "aside" . }}default{{ end }}{{ template "__end" . }}{{ end }}`,
+			"pages/home/index.html": `{{ define "content" }}Home {{ . }}{{ end }}{{ define "aside" }}home aside{{ end }}`,
+			"pages/home/about.html": `{{ define "content" }}About {{ . }}{{ end }}`,
+		}), []string{"app_layout"}},
+	}
+	for _, fixture := range fixtures {
+		cloned, err := gotemp.New(fixture.dir)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		shared, err := gotemp.New(fixture.dir, gotemp.WithSharedTemplates(true))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for _, page := range cloned.ListPages() {
+			for _, layout := range fixture.layouts {
+				var want, got bytes.Buffer
+				wantErr := cloned.RenderPage(&want, layout, page, "Ada")
+				gotErr := shared.RenderPage(&got, layout, page, "Ada")
+				if (wantErr == nil) != (gotErr == nil) || want.String() != got.String() {
+					t.Errorf("%s %s %s: expected %q (%v), got %q (%v)", fixture.dir, layout, page, want.String(), wantErr, got.String(), gotErr)
+				}
+			}
+			wantNames, _ := cloned.PageTemplates(page)
+			gotNames, _ := shared.PageTemplates(page)
+			if fmt.Sprint(wantNames) != fmt.Sprint(gotNames) {
+				t.Errorf("%s %s: expected templates %v, got %v", fixture.dir, page, wantNames, gotNames)
+			}
+		}
+	}
+}
+
+func BenchmarkSharedTemplates(b *testing.B) {
+	files := map[string]string{
+		"layouts/app.html": `{{ define "app_layout" }}{{ template "__start" . }}<nav>` + strings.Repeat(`<a href="/{{ .Name }}">{{ .Name }}</a>`, 50) + `</nav>{{ block "content" . }}{{ end }}{{ template "__end" . }}{{ end }}`,
+	}
+	for i := range 20 {
+		files[fmt.Sprintf("partials/_widget%d.html", i)] = strings.Repeat(`<div class="widget">{{ if .Name }}{{ .Name }}{{ else }}none{{ end }}</div>`, 20)
+	}
+	for i := range 200 {
+		files[fmt.Sprintf("pages/section/page%d.html", i)] = fmt.Sprintf(`{{ define "content" }}<h1>Page %d</h1>%s{{ end }}`, i, strings.Repeat(`<p>{{ .Name }}</p>`, 20))
+	}
+	dir := writeTemplates(b, files)
+
+	for _, shared := range []bool{false, true} {
+		b.Run(fmt.Sprintf("shared=%t", shared), func(b *testing.B) {
+			var g *gotemp.Gotemp
+			var before, after runtime.MemStats
+			for b.Loop() {
+				g = nil
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				var err error
+				if g, err = gotemp.New(dir, gotemp.WithSharedTemplates(shared)); err != nil {
+					b.Fatal(err)
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+			}
+			b.ReportMetric(float64(after.HeapAlloc)-float64(before.HeapAlloc), "heap-bytes")
+			runtime.KeepAlive(g)
+		})
+	}
+}
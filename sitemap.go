@@ -0,0 +1,91 @@
+package gotemp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+func (tc *Gotemp) ListPages() []string {
+	pages := tc.set.Load().pages
+	names := make([]string, 0, len(pages))
+	for name := range pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (tc *Gotemp) GenerateSitemap(baseURL string, w io.Writer) error {
+	pages := tc.set.Load().pages
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	var urlSet sitemapURLSet
+	for _, name := range tc.ListPages() {
+		if tc.sitemapExcluded(name) {
+			continue
+		}
+		url := sitemapURL{Loc: baseURL + pageRoute(pages[name].path)}
+		if modTime := pages[name].modTime; !modTime.IsZero() {
+			url.LastMod = modTime.UTC().Format(time.RFC3339)
+		}
+		urlSet.URLs = append(urlSet.URLs, url)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(urlSet); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (tc *Gotemp) SitemapHandler(baseURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := tc.GenerateSitemap(baseURL, &buf); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		buf.WriteTo(w)
+	})
+}
+
+func (tc *Gotemp) sitemapExcluded(page string) bool {
+	if page == tc.notFoundPage || page == tc.serverErrorPage {
+		return true
+	}
+	for _, pattern := range tc.sitemapExclude {
+		if matched, _ := path.Match(pattern, page); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func pageRoute(page string) string {
+	if dir, file := path.Split(page); file == "index.html" {
+		return "/" + dir
+	}
+	return "/" + strings.TrimSuffix(page, ".html")
+}
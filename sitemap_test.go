@@ -0,0 +1,88 @@
+package gotemp_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestListPages(t *testing.T) {
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := []string{"auth/sign_in.html", "home/index.html"}
+	if got := g.ListPages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestGenerateSitemap(t *testing.T) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"pages/home/index.html":    `{{ define "content" }}Home{{ end }}`,
+		"pages/blog/first.html":    `{{ define "content" }}First{{ end }}`,
+		"pages/drafts/next.html":   `{{ define "content" }}Next{{ end }}`,
+		"pages/errors/404.html":    `{{ define "content" }}Not found{{ end }}`,
+		"pages/_shared/cards.html": `{{ define "card" }}Card{{ end }}`,
+	}),
+		gotemp.WithErrorPages("errors/404.html", ""),
+		gotemp.WithSitemapExclude("drafts/*"),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf strings.Builder
+	if err := g.GenerateSitemap("https://example.com/", &buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("expected XML declaration, got %q", buf.String())
+	}
+
+	var sitemap struct {
+		XMLName xml.Name
+		URLs    []struct {
+			Loc     string `xml:"loc"`
+			LastMod string `xml:"lastmod"`
+		} `xml:"url"`
+	}
+	if err := xml.Unmarshal([]byte(buf.String()), &sitemap); err != nil {
+		t.Fatalf("expected valid XML, got %v", err)
+	}
+	if sitemap.XMLName.Space != "http://www.sitemaps.org/schemas/sitemap/0.9" || sitemap.XMLName.Local != "urlset" {
+		t.Errorf("expected sitemap urlset root, got %v", sitemap.XMLName)
+	}
+	var locs []string
+	for _, url := range sitemap.URLs {
+		locs = append(locs, url.Loc)
+		if url.LastMod == "" {
+			t.Errorf("expected lastmod for %s", url.Loc)
+		}
+	}
+	want := []string{"https://example.com/blog/first", "https://example.com/home/"}
+	if !reflect.DeepEqual(locs, want) {
+		t.Errorf("expected %v, got %v", want, locs)
+	}
+}
+
+func TestSitemapHandler(t *testing.T) {
+	g, err := gotemp.New("examples")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	g.SitemapHandler("https://example.com").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/xml") {
+		t.Errorf("expected XML content type, got %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "<loc>https://example.com/auth/sign_in</loc>") {
+		t.Errorf("expected page URL in sitemap, got %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,76 @@
+package gotemp
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+)
+
+const (
+	splitPrefix   = "_gotemp_split_"
+	splitPageFunc = "_gotempSplitPage"
+)
+
+func (tc *Gotemp) RenderPageSplit(w io.Writer, layout string, layoutData any, page string, pageData any) error {
+	if err := tc.reloadIfChanged(); err != nil {
+		return err
+	}
+	set := tc.set.Load()
+	pageEntry := set.pages[page]
+	if pageEntry == nil {
+		return fmt.Errorf("%w: %s", ErrPageNotFound, page)
+	}
+	if err := pageEntry.ready(); err != nil {
+		return err
+	}
+	layout = tc.pageLayout(pageEntry, layout)
+	pageData, err := tc.renderData(pageEntry, pageData)
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	data, err := tc.renderData(pageEntry, map[string]any{"Layout": layoutData})
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	t, err := pageEntry.clone(layout)
+	if err != nil {
+		return err
+	}
+	defines, err := parseTrees(path.Base(path.Join("pages", pageEntry.path)), pageEntry.source)
+	if err != nil {
+		return err
+	}
+	if defines["content"] == nil {
+		return fmt.Errorf("%w: content in page %s", ErrBlockNotFound, page)
+	}
+	t.Funcs(template.FuncMap{splitPageFunc: func() any { return pageData }})
+	for _, name := range slices.Sorted(maps.Keys(defines)) {
+		entry := pageEntry.entry(t, name)
+		def := t.Lookup(entry)
+		if name == path.Base(pageEntry.path) || def == nil || def.Tree == nil {
+			continue
+		}
+		split := splitPrefix + entry
+		if _, err := t.AddParseTree(split, def.Tree.Copy()); err != nil {
+			return err
+		}
+		trees, err := parseTrees(entry, "{{ template "+strconv.Quote(split)+" "+splitPageFunc+" }}")
+		if err != nil {
+			return err
+		}
+		if _, err := t.AddParseTree(entry, trees[entry]); err != nil {
+			return err
+		}
+	}
+	tc.bindRender(t, set.partials, newRenderState(pageEntry.meta.Page))
+
+	err = tc.execute(w, t, pageEntry.entry(t, layout), data, true)
+	if err != nil {
+		return fmt.Errorf("page %s: %w", page, err)
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package gotemp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestRenderPageSplit(t *testing.T) {
+	for _, shared := range []bool{false, true} {
+		g, err := gotemp.New(writeTemplates(t, map[string]string{
+			"layouts/app.html": `{{ define "app_layout" }}<title>{{ block "title" . }}{{ end }}</title>` +
+				`<nav>{{ .Layout.User }}{{ .Layout.Title }}{{ with .Page }}leak{{ end }}</nav>{{ block "content" . }}{{ end }}{{ end }}`,
+			"pages/home/index.html": `{{ define "title" }}{{ .Title }}{{ end }}` +
+				`{{ define "content" }}<main>{{ .Title }}{{ .User }}{{ partial "tag.html" .Title }}</main>{{ end }}`,
+			"partials/tag.html": `{{ define "tag" }}<b>{{ . }}</b>{{ end }}`,
+		}), gotemp.WithSharedTemplates(shared))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var buf strings.Builder
+		layoutData := map[string]any{"User": "ada"}
+		pageData := map[string]any{"Title": "Home"}
+		if err := g.RenderPageSplit(&buf, "app_layout", layoutData, "home/index.html", pageData); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if want := "<title>Home</title><nav>ada</nav><main>Home<b>Home</b></main>"; buf.String() != want {
+			t.Errorf("expected the layout and page to each see their own data, got %q, want %q", buf.String(), want)
+		}
+
+		buf.Reset()
+		if err := g.RenderPage(&buf, "app_layout", "home/index.html", map[string]any{"Layout": layoutData, "Title": "Plain", "User": "bob"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if want := "<title>Plain</title><nav>ada</nav><main>Plainbob<b>Plain</b></main>"; buf.String() != want {
+			t.Errorf("expected the split render to leave the page template untouched, got %q, want %q", buf.String(), want)
+		}
+	}
+}
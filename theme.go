@@ -0,0 +1,73 @@
+package gotemp
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"slices"
+)
+
+func (tc *Gotemp) loadThemes() error {
+	entries, err := fs.ReadDir(tc.fsys, "themes")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read themes: %w", err)
+	}
+	opts := append(slices.Clone(tc.opts), WithThemeSelector(nil))
+	themes := make(map[string]*Gotemp)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		engine, err := tc.loadTheme(entry.Name(), opts)
+		if err != nil {
+			for _, engine := range themes {
+				engine.Close()
+			}
+			return fmt.Errorf("theme %s: %w", entry.Name(), err)
+		}
+		themes[entry.Name()] = engine
+	}
+	tc.themes = themes
+	return nil
+}
+
+func (tc *Gotemp) loadTheme(name string, opts []Option) (*Gotemp, error) {
+	theme, err := fs.Sub(tc.fsys, path.Join("themes", name))
+	if err != nil {
+		return nil, err
+	}
+	return newGotemp(tc.basePath, overlayFS{upper: theme, lower: tc.fsys}, append(slices.Clip(opts), withThemeName(name)))
+}
+
+func (tc *Gotemp) themeEngine(r *http.Request) *Gotemp {
+	if tc.themeSelector == nil || r == nil {
+		return tc
+	}
+	if engine := tc.themes[tc.themeSelector(r)]; engine != nil {
+		return engine
+	}
+	return tc
+}
+
+func (tc *Gotemp) themed(layout string, handler func(*Gotemp, string) http.Handler) http.Handler {
+	base := handler(tc, layout)
+	if tc.themeSelector == nil {
+		return base
+	}
+	handlers := make(map[string]http.Handler, len(tc.themes))
+	for name, engine := range tc.themes {
+		handlers[name] = handler(engine, layout)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if themed := handlers[tc.themeSelector(r)]; themed != nil {
+			themed.ServeHTTP(w, r)
+			return
+		}
+		base.ServeHTTP(w, r)
+	})
+}
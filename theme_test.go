@@ -0,0 +1,131 @@
+package gotemp_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestThemeSelector(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"partials/_theme.html":             `{{ define "theme" }}light{{ end }}`,
+		"layouts/app.html":                 `{{ define "app_layout" }}<body class="{{ template "theme" . }}">{{ block "content" . }}{{ end }}</body>{{ end }}`,
+		"pages/home/index.html":            `{{ define "content" }}Home{{ end }}`,
+		"themes/dark/partials/_theme.html": `{{ define "theme" }}dark{{ end }}`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithThemeSelector(func(r *http.Request) string {
+		if cookie, err := r.Cookie("theme"); err == nil {
+			return cookie.Value
+		}
+		return ""
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer g.Close()
+
+	for _, h := range []http.Handler{g.Handler("app_layout"), g.HTMXHandler("app_layout")} {
+		for theme, want := range map[string]string{
+			"":        `<body class="light">Home</body>`,
+			"dark":    `<body class="dark">Home</body>`,
+			"unknown": `<body class="light">Home</body>`,
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/home/index", nil)
+			if theme != "" {
+				req.AddCookie(&http.Cookie{Name: "theme", Value: theme})
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Body.String() != want {
+				t.Errorf("theme %q: expected %q, got %q", theme, want, rec.Body.String())
+			}
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/home/index", nil)
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	var buf strings.Builder
+	if err := g.RenderPageRequest(&buf, req, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != `<body class="dark">Home</body>` {
+		t.Errorf("expected RenderPageRequest to use the theme, got %q", buf.String())
+	}
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != `<body class="light">Home</body>` {
+		t.Errorf("expected RenderPage to use the base templates, got %q", buf.String())
+	}
+}
+
+func TestThemeLoadFailureClosesThemes(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"pages/home/index.html":         `{{ define "content" }}Home{{ end }}`,
+		"themes/a/partials/_theme.html": `{{ define "theme" }}a{{ end }}`,
+		"themes/b/partials/_theme.html": `{{ define "theme" }}b{{ end`,
+	})
+	before := runtime.NumGoroutine()
+	_, err := gotemp.New(dir, gotemp.WithPollReload(time.Hour), gotemp.WithThemeSelector(func(*http.Request) string { return "" }))
+	if err == nil || !strings.Contains(err.Error(), "theme b") {
+		t.Fatalf("expected the broken theme to fail the load, got %v", err)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("expected the loaded themes to be closed, %d goroutines before and %d after", before, after)
+	}
+}
+
+func TestThemesFollowRuntimeEdits(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"partials/_theme.html":             `{{ define "theme" }}light{{ end }}`,
+		"partials/_badge.html":             `{{ define "badge" }}<b>v1</b>{{ end }}`,
+		"layouts/app.html":                 `{{ define "app_layout" }}<body class="{{ template "theme" . }}">{{ block "content" . }}{{ end }}{{ template "badge" . }}</body>{{ end }}`,
+		"pages/home/index.html":            `{{ define "content" }}{{ greet "Ada" }}{{ end }}`,
+		"themes/dark/partials/_theme.html": `{{ define "theme" }}dark{{ end }}`,
+	})
+	g, err := gotemp.New(dir, gotemp.WithFuncs(template.FuncMap{"greet": func(name string) string { return "Hello, " + name }}),
+		gotemp.WithThemeSelector(func(r *http.Request) string { return r.Header.Get("X-Theme") }))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer g.Close()
+	render := func() string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/home/", nil)
+		req.Header.Set("X-Theme", "dark")
+		rec := httptest.NewRecorder()
+		g.Handler("app_layout").ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	if err := g.SetFuncs(template.FuncMap{"greet": func(name string) string { return "Welcome back, " + name }}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out := render(); out != `<body class="dark">Welcome back, Ada<b>v1</b></body>` {
+		t.Errorf("expected SetFuncs to reach the theme, got %q", out)
+	}
+	if err := g.UpdateTemplate("pages/home/index.html", `{{ define "content" }}Edited {{ greet "Ada" }}{{ end }}`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out := render(); out != `<body class="dark">Edited Welcome back, Ada<b>v1</b></body>` {
+		t.Errorf("expected UpdateTemplate to reach the theme, got %q", out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "partials/_badge.html"), []byte(`{{ define "badge" }}<b>v2</b>{{ end }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ReloadPartial("_badge.html"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if out := render(); out != `<body class="dark">Edited Welcome back, Ada<b>v2</b></body>` {
+		t.Errorf("expected ReloadPartial to reach the theme, got %q", out)
+	}
+}
@@ -0,0 +1,102 @@
+package gotemp
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+const (
+	tracePrefix = "_gotemp_trace_"
+	traceFunc   = "_gotempTrace"
+)
+
+type RenderTrace struct {
+	Page      string
+	Layout    string
+	Templates []string
+	Embeds    []string
+}
+
+func (tc *Gotemp) RenderPageTrace(w io.Writer, layout, page string, data any) (*RenderTrace, error) {
+	if err := tc.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+	set := tc.set.Load()
+	pageEntry := set.pages[page]
+	if pageEntry == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPageNotFound, page)
+	}
+	if err := pageEntry.ready(); err != nil {
+		return nil, err
+	}
+	layout = tc.pageLayout(pageEntry, layout)
+	data, err := tc.renderData(pageEntry, data)
+	if err != nil {
+		return nil, fmt.Errorf("page %s: %w", page, err)
+	}
+	t, err := pageEntry.clone(layout)
+	if err != nil {
+		return nil, err
+	}
+	trace := &RenderTrace{Page: page, Layout: layout, Templates: []string{layout}}
+	record := func(list *[]string, name string) {
+		if !slices.Contains(*list, name) {
+			*list = append(*list, name)
+		}
+	}
+	if err := traceDefines(t); err != nil {
+		return nil, err
+	}
+	state := newRenderState(pageEntry.meta.Page)
+	cached := tc.profiled(tc.cachedPartialFunc(t, set.partials, state))
+	embed := tc.renderPageFunc(state)
+	tc.bindRender(t, set.partials, state).Funcs(template.FuncMap{
+		traceFunc: func(name string) bool {
+			if name, ok := pageEntry.visibleName(name); ok {
+				record(&trace.Templates, name)
+			}
+			return false
+		},
+		"cachedPartial": func(name string, data any) (template.HTML, error) {
+			if entrypoint, ok := set.partials[name]; ok {
+				record(&trace.Templates, entrypoint)
+			}
+			return cached(name, data)
+		},
+		embedFunc: func(name string, data any) (template.HTML, error) {
+			record(&trace.Embeds, name)
+			return embed(name, data)
+		},
+	})
+
+	err = tc.execute(w, t, pageEntry.entry(t, layout), data, true)
+	if err != nil {
+		return trace, fmt.Errorf("page %s: %w", page, err)
+	}
+	return trace, err
+}
+
+func traceDefines(t *template.Template) error {
+	for _, def := range t.Templates() {
+		name := def.Name()
+		if def.Tree == nil || parse.IsEmptyTree(def.Tree.Root) || strings.HasPrefix(name, tracePrefix) {
+			continue
+		}
+		if _, err := t.AddParseTree(tracePrefix+name, def.Tree.Copy()); err != nil {
+			return err
+		}
+		trees, err := parseTrees(name, "{{ if "+traceFunc+" "+strconv.Quote(name)+" }}{{ end }}{{ template "+strconv.Quote(tracePrefix+name)+" . }}")
+		if err != nil {
+			return err
+		}
+		if _, err := t.AddParseTree(name, trees[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
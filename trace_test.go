@@ -0,0 +1,74 @@
+package gotemp_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bllyanos/gotemp"
+)
+
+func TestRenderPageTrace(t *testing.T) {
+	for _, shared := range []bool{false, true} {
+		testRenderPageTrace(t, shared)
+	}
+}
+
+func testRenderPageTrace(t *testing.T, shared bool) {
+	g, err := gotemp.New(writeTemplates(t, map[string]string{
+		"layouts/app.html":       `{{ define "app_layout" }}<body>{{ block "content" . }}{{ end }}{{ template "footer" . }}</body>{{ end }}`,
+		"partials/_footer.html":  `{{ define "footer" }}<footer>(c)</footer>{{ end }}{{ define "unused" }}unused{{ end }}`,
+		"partials/card.html":     `<div class="card">{{ . }}</div>`,
+		"partials/_badge.html":   `{{ define "badge" }}<b>{{ . }}</b>{{ end }}`,
+		"pages/home/index.html":  `{{ define "content" }}{{ range .Cards }}{{ partial "card.html" . }}{{ end }}{{ if .Badge }}{{ template "badge" . }}{{ end }}{{ end }}`,
+		"pages/home/about.html":  `{{ define "content" }}About{{ end }}`,
+		"pages/home/script.html": `{{ define "content" }}<script>{{ template "vars" . }}var n = {{ .N }};</script>{{ renderPage "home/about.html" . }}{{ end }}{{ define "vars" }}var a = {{ .A }};{{ end }}`,
+	}), gotemp.WithSharedTemplates(shared))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf strings.Builder
+	trace, err := g.RenderPageTrace(&buf, "app_layout", "home/index.html", map[string]any{"Cards": []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := `<body><div class="card">a</div><div class="card">b</div><footer>(c)</footer></body>`
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+	if trace.Page != "home/index.html" || trace.Layout != "app_layout" {
+		t.Errorf("expected page and layout in the trace, got %+v", trace)
+	}
+	if got := fmt.Sprint(trace.Templates); got != "[app_layout content card.html footer]" {
+		t.Errorf("expected executed templates in order, got %s", got)
+	}
+
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "home/index.html", map[string]any{"Cards": []string{"a", "b"}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.String() != want {
+		t.Errorf("expected regular renders to be unaffected, got %q", buf.String())
+	}
+
+	data := map[string]any{"A": "x", "N": 1}
+	buf.Reset()
+	if err := g.RenderPage(&buf, "app_layout", "home/script.html", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var traced strings.Builder
+	trace, err = g.RenderPageTrace(&traced, "app_layout", "home/script.html", data)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if traced.String() != buf.String() {
+		t.Errorf("expected traced script output %q, got %q", buf.String(), traced.String())
+	}
+	if got := fmt.Sprint(trace.Templates); got != "[app_layout content vars footer]" {
+		t.Errorf("expected defines run with {{ template }} in the trace, got %s", got)
+	}
+	if got := fmt.Sprint(trace.Embeds); got != "[home/about.html]" {
+		t.Errorf("expected embedded pages in the trace, got %s", got)
+	}
+}